@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/spf13/cobra"
+)
+
+var balanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "Show the confirmed balances of the accounts of a wallet",
+	Long: `Show the confirmed balance and pending amount of every account of a wallet,
+or of all wallets if no wallet is specified, followed by the total of each
+wallet. Amounts are shown in the units of the wallet's network.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if walletIndex >= 0 {
+			checkWalletIndex()
+		}
+		var indices []int
+		var accounts []string
+		for i, wi := range wallets {
+			if (walletIndex < 0 || i == walletIndex) && len(wi.Accounts) > 0 {
+				indices = append(indices, i)
+				for address := range wi.Accounts {
+					accounts = append(accounts, address)
+				}
+			}
+		}
+		if len(accounts) == 0 {
+			return
+		}
+		rpcClient := rpc.Client{URL: rpcURL}
+		balances, err := rpcClient.AccountsBalancesWithOptions(accounts, rpc.AccountBalanceOptions{IncludeOnlyConfirmed: true})
+		fatalIf(err)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "Account\tBalance\tPending\t")
+		for _, i := range indices {
+			wi := wallets[i]
+			wi.init()
+			var addresses []string
+			for address := range wi.Accounts {
+				addresses = append(addresses, address)
+			}
+			sort.Strings(addresses)
+			var balanceSum, pendingSum big.Int
+			for _, address := range addresses {
+				balance, pending := new(big.Int), new(big.Int)
+				if b := balances[address]; b != nil {
+					if b.Balance != nil {
+						balance = &b.Balance.Int
+					}
+					if b.Pending != nil {
+						pending = &b.Pending.Int
+					}
+				}
+				balanceSum.Add(&balanceSum, balance)
+				pendingSum.Add(&pendingSum, pending)
+				fmt.Fprintf(tw, "%s\t%s\t%s\t\n", address, wi.w.FormatAmount(balance), wi.w.FormatAmount(pending))
+			}
+			total := "Total"
+			if walletIndex < 0 {
+				total = fmt.Sprintf("Total of wallet %d", i)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", total, wi.w.FormatAmount(&balanceSum), wi.w.FormatAmount(&pendingSum))
+		}
+		tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(balanceCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/spf13/cobra"
+)
+
+var historyCount int64
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the transaction history of an account",
+	Long: `Show the transaction history of an account, most recent first.
+
+  history [account]
+
+If no account is given, the account given by --account is used.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		account := walletAccount
+		if len(args) > 0 {
+			account = args[0]
+		}
+		if account == "" {
+			fatal("account must be specified")
+		}
+		rpcClient := rpc.Client{URL: rpcURL}
+		history, _, err := rpcClient.AccountHistory(account, historyCount, nil)
+		fatalIf(err)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, h := range history {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				time.Unix(int64(h.LocalTimestamp), 0).Format("2006-01-02 15:04:05"),
+				h.Type, h.Amount.Nano(), h.Account, h.Hash)
+		}
+		tw.Flush()
+	},
+}
+
+func init() {
+	historyCmd.Flags().Int64VarP(&historyCount, "count", "n", 10, "Number of transactions to show (-1 for all)")
+	rootCmd.AddCommand(historyCmd)
+}
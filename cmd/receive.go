@@ -1,32 +1,38 @@
 package cmd
 
 import (
-	"math/big"
-
+	"github.com/hectorchu/gonano/util"
 	"github.com/spf13/cobra"
 )
 
+var receiveMin string
+
 var receiveCmd = &cobra.Command{
 	Use:   "receive",
 	Short: "Receive all pending amounts for a wallet or account",
 	Run: func(cmd *cobra.Command, args []string) {
+		min, err := util.NanoAmountFromString(receiveMin)
+		fatalIf(err)
 		if walletAccount == "" {
 			checkWalletIndex()
 			wi := wallets[walletIndex]
 			wi.init()
+			indices := make([]uint32, 0, len(wi.Accounts))
 			for _, index := range wi.Accounts {
-				_, err := wi.w.NewAccount(&index)
-				fatalIf(err)
+				indices = append(indices, index)
 			}
-			err := wi.w.ReceivePendings(big.NewInt(0))
+			_, err := wi.w.LoadAccounts(indices)
+			fatalIf(err)
+			err = wi.w.ReceivePendings(min.Raw)
 			fatalIf(err)
 		} else {
-			err := getAccount().ReceivePendings(big.NewInt(0))
+			err := getAccount().ReceivePendings(min.Raw)
 			fatalIf(err)
 		}
 	},
 }
 
 func init() {
+	receiveCmd.Flags().StringVar(&receiveMin, "min", "0", "Minimum amount of Nano to receive, smaller amounts are left pending")
 	rootCmd.AddCommand(receiveCmd)
 }
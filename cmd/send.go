@@ -1,29 +1,65 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/hectorchu/gonano/util"
 	"github.com/spf13/cobra"
 )
 
+var sendTo, sendAmount string
+var sendWait time.Duration
+
 var sendCmd = &cobra.Command{
 	Use:   "send",
 	Short: "Send an amount of Nano from an account",
 	Long: `Send an amount of Nano from an account.
 
-  send <destination> <amount>`,
-	Args: cobra.ExactArgs(2),
+  send <destination> <amount>
+  send --to <destination> --amount <amount>
+
+The block hash is printed, followed by whether the send was confirmed within
+the time given by --wait.`,
+	Args: cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 2 {
+			sendTo, sendAmount = args[0], args[1]
+		}
+		if len(args) == 1 || sendTo == "" || sendAmount == "" {
+			fatal("destination and amount are required")
+		}
 		a := getAccount()
-		amount, err := util.NanoAmountFromString(args[1])
-		fatalIf(err)
-		hash, err := a.Send(args[0], amount.Raw)
+		amount, err := util.NanoAmountFromString(sendAmount)
 		fatalIf(err)
+		if sendWait <= 0 {
+			hash, err := a.Send(sendTo, amount.Raw)
+			fatalIf(err)
+			fmt.Println(hash)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), sendWait)
+		defer cancel()
+		hash, err := a.SendAndConfirm(ctx, sendTo, amount.Raw)
+		if hash == nil {
+			fatal(err)
+		}
 		fmt.Println(hash)
+		switch err {
+		case nil:
+			fmt.Println("confirmed")
+		case context.DeadlineExceeded:
+			fmt.Println("not confirmed after", sendWait)
+		default:
+			fatal(err)
+		}
 	},
 }
 
 func init() {
+	sendCmd.Flags().StringVar(&sendTo, "to", "", "Destination account")
+	sendCmd.Flags().StringVar(&sendAmount, "amount", "", "Amount of Nano to send")
+	sendCmd.Flags().DurationVar(&sendWait, "wait", time.Minute, "How long to wait for the send to be confirmed (0 to not wait)")
 	rootCmd.AddCommand(sendCmd)
 }
@@ -57,7 +57,7 @@ func checkWalletAccount() {
 }
 
 func initNewWallet() (wi *walletInfo) {
-	seed := string(readPassword("Enter seed or bip39 mnemonic (leave blank for random): "))
+	seed := wallet.NormalizeMnemonic(string(readPassword("Enter seed or bip39 mnemonic (leave blank for random): ")))
 	password := readPassword("Enter password: ")
 	password2 := readPassword("Re-enter password: ")
 	if !bytes.Equal(password, password2) {
@@ -81,13 +81,17 @@ func initNewWallet() (wi *walletInfo) {
 		fmt.Println("Your secret words are:", mnemonic)
 		initBip39(entropy)
 	} else {
-		seed2, err := hex.DecodeString(seed)
-		if err == nil {
+		// Anything that is valid hex is taken to be a seed, so that a seed of
+		// the wrong length isn't reported as an unknown mnemonic word.
+		if _, err := hex.DecodeString(seed); err == nil {
+			seed2, err := wallet.ParseSeedHex(seed)
+			fatalIf(err)
 			enc, err := encrypt(seed2, key)
 			fatalIf(err)
 			wi.Seed = hex.EncodeToString(enc)
 			wi.initRegularSeed(seed2)
 		} else {
+			fatalIf(wallet.ValidateMnemonic(seed))
 			entropy, err := bip39.EntropyFromMnemonic(seed)
 			fatalIf(err)
 			initBip39(entropy)
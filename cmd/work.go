@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hectorchu/gonano/pow"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/wallet"
+	"github.com/spf13/cobra"
+)
+
+var workDifficulty string
+
+var workCmd = &cobra.Command{
+	Use:   "work",
+	Short: "Generate proof-of-work for a block hash",
+	Long: `Generate proof-of-work for a block hash, or for an account address in the
+case of an open block. The work is generated by the work RPC endpoint,
+falling back to the CPU, and is validated before it is printed.
+
+  work <hash|address>`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := rpc.ParseBlockHash(args[0])
+		if err != nil {
+			data, err = util.AddressToPubkey(args[0])
+		}
+		fatalIf(err)
+		difficulty, err := rpc.ParseHexData(workDifficulty)
+		fatalIf(err)
+		if len(difficulty) != 8 {
+			fatal("difficulty must be 8 bytes")
+		}
+		var work []byte
+		if rpcWorkURL != "" {
+			client := rpc.Client{URL: rpcWorkURL}
+			if work, _, _, err = client.WorkGenerate(data, difficulty); err != nil || !pow.Validate(data, work, difficulty) {
+				work = nil
+			}
+		}
+		if work == nil {
+			work, err = pow.Generate(data, difficulty)
+			fatalIf(err)
+		}
+		fmt.Println(hex.EncodeToString(work))
+	},
+}
+
+func init() {
+	workCmd.Flags().StringVarP(&workDifficulty, "difficulty", "d", wallet.DefaultSendDifficulty.String(), "Work difficulty in hex")
+	rootCmd.AddCommand(workCmd)
+}
@@ -5,11 +5,13 @@ import (
 	"hash"
 	"math/rand"
 	"runtime"
+	"sync"
 
 	"golang.org/x/crypto/blake2b"
 )
 
-// Generate generates proof-of-work.
+// Generate generates proof-of-work. work is the big-endian encoding of the
+// 64-bit work value, which is the byte order of the work field of blocks.
 func Generate(data, difficulty []byte) (work []byte, err error) {
 	target := binary.BigEndian.Uint64(difficulty)
 	work, err = GenerateCPU(data, target)
@@ -19,27 +21,52 @@ func Generate(data, difficulty []byte) (work []byte, err error) {
 	return
 }
 
-// GenerateCPU generates proof-of-work using the CPU.
+// Validate reports whether work is valid proof-of-work for data at
+// difficulty. work is in the byte order returned by Generate, which is the
+// order used for the work field of blocks.
+func Validate(data, work, difficulty []byte) bool {
+	if len(work) != 8 || len(difficulty) != 8 {
+		return false
+	}
+	h := hashPool.Get().(hash.Hash)
+	defer hashPool.Put(h)
+	h.Reset()
+	var nonce, sum [8]byte
+	for i := range work {
+		nonce[i] = work[len(work)-1-i]
+	}
+	h.Write(nonce[:])
+	h.Write(data)
+	return binary.LittleEndian.Uint64(h.Sum(sum[:0])) >= binary.BigEndian.Uint64(difficulty)
+}
+
+// hashPool holds work hashers for reuse, so that validating and generating
+// work doesn't allocate a hasher each time.
+var hashPool = sync.Pool{New: func() interface{} {
+	h, _ := blake2b.New(8, nil)
+	return h
+}}
+
+// GenerateCPU generates proof-of-work using the CPU. Unlike Generate, work is
+// returned in the order it is hashed in, which is the little-endian encoding
+// of the work value and must be reversed before it is put in a block.
 func GenerateCPU(data []byte, target uint64) (work []byte, err error) {
 	n := runtime.NumCPU()
 	ch := make(chan []byte, n)
-	hash := make([]hash.Hash, n)
-	for i := 0; i < n; i++ {
-		if hash[i], err = blake2b.New(8, nil); err != nil {
-			return
-		}
-	}
 	done := false
 	x := rand.Uint64()
 	for i := 0; i < n; i++ {
 		go func(i int) {
+			h := hashPool.Get().(hash.Hash)
+			defer hashPool.Put(h)
 			work := make([]byte, 8)
+			var sum [8]byte
 			for x := x + uint64(i); !done; x += uint64(n) {
 				binary.BigEndian.PutUint64(work, x)
-				hash[i].Reset()
-				hash[i].Write(work)
-				hash[i].Write(data)
-				if binary.LittleEndian.Uint64(hash[i].Sum(nil)) >= target {
+				h.Reset()
+				h.Write(work)
+				h.Write(data)
+				if binary.LittleEndian.Uint64(h.Sum(sum[:0])) >= target {
 					done = true
 					ch <- work
 				}
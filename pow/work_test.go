@@ -2,6 +2,7 @@ package pow_test
 
 import (
 	"encoding/binary"
+	"encoding/hex"
 	"math/rand"
 	"strconv"
 	"testing"
@@ -23,3 +24,39 @@ func TestGenerateCPU(t *testing.T) {
 	hash.Write(data)
 	assert.True(t, binary.LittleEndian.Uint64(hash.Sum(nil)) >= target)
 }
+
+func TestValidate(t *testing.T) {
+	data, _ := hex.DecodeString("CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E")
+	work, _ := hex.DecodeString("788f7ec074f1854b")
+	receive, _ := hex.DecodeString("fffffe0000000000")
+	assert.True(t, pow.Validate(data, work, receive))
+	work[0] ^= 1
+	assert.False(t, pow.Validate(data, work, receive))
+
+	data = make([]byte, 32)
+	rand.Read(data)
+	easy, _ := hex.DecodeString("ff00000000000000")
+	work, err := pow.Generate(data, easy)
+	require.Nil(t, err)
+	assert.True(t, pow.Validate(data, work, easy))
+	assert.False(t, pow.Validate(data, work[:4], easy))
+}
+
+func BenchmarkValidate(b *testing.B) {
+	data, _ := hex.DecodeString("CEC5287A00F5A50E8F5A5EE3F6A4E49E4EEA0ADD24D5E5B1E7E0E5A56C1F0C4F")
+	work, _ := hex.DecodeString("788f7ec074f1854b")
+	difficulty, _ := hex.DecodeString("fffffe0000000000")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pow.Validate(data, work, difficulty)
+	}
+}
+
+func BenchmarkGenerateCPU(b *testing.B) {
+	data := make([]byte, 32)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rand.Read(data)
+		pow.GenerateCPU(data, 0xfff0000000000000)
+	}
+}
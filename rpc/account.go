@@ -1,19 +1,46 @@
 package rpc
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
 	"time"
+
+	"github.com/hectorchu/gonano/util"
 )
 
 // AccountBalance returns how many RAW is owned and how many have not yet been received by account.
 func (c *Client) AccountBalance(account string) (balance, pending *RawAmount, err error) {
-	resp, err := c.send(map[string]interface{}{"action": "account_balance", "account": account})
+	b, err := c.AccountBalanceWithOptions(account, AccountBalanceOptions{})
+	return b.Balance, b.Pending, err
+}
+
+// AccountBalanceOptions are the options for AccountBalanceWithOptions and
+// AccountsBalancesWithOptions.
+type AccountBalanceOptions struct {
+	// IncludeOnlyConfirmed asks for balances as of the latest confirmed
+	// blocks, and pending amounts from confirmed sends only. This is the
+	// default from node V22, but older nodes need it to be set explicitly.
+	IncludeOnlyConfirmed bool
+}
+
+func (opts AccountBalanceOptions) body(body map[string]interface{}) map[string]interface{} {
+	if opts.IncludeOnlyConfirmed {
+		body["include_only_confirmed"] = true
+	}
+	return body
+}
+
+// AccountBalanceWithOptions is like AccountBalance, with the options in opts.
+func (c *Client) AccountBalanceWithOptions(account string, opts AccountBalanceOptions) (balance AccountBalance, err error) {
+	resp, err := c.send(opts.body(map[string]interface{}{"action": "account_balance", "account": account}))
 	if err != nil {
 		return
 	}
-	var v struct{ Balance, Pending *RawAmount }
-	err = json.Unmarshal(resp, &v)
-	return v.Balance, v.Pending, err
+	err = json.Unmarshal(resp, &balance)
+	return
 }
 
 // AccountBlockCount gets the number of blocks for a specific account.
@@ -23,59 +50,133 @@ func (c *Client) AccountBlockCount(account string) (blockCount uint64, err error
 		return
 	}
 	var v struct {
-		BlockCount uint64 `json:"block_count,string"`
+		BlockCount Uint64 `json:"block_count"`
 	}
 	err = json.Unmarshal(resp, &v)
-	return v.BlockCount, err
+	return uint64(v.BlockCount), err
 }
 
 // AccountHistory reports send/receive information for an account.
 func (c *Client) AccountHistory(account string, count int64, head BlockHash) (history []AccountHistory, previous BlockHash, err error) {
+	history, previous, _, err = c.AccountHistoryWithOptions(account, count, AccountHistoryOptions{Head: head})
+	return
+}
+
+// AccountHistoryRaw reports all parameters of the block itself as seen in
+// BlockCreate or other APIs returning blocks.
+func (c *Client) AccountHistoryRaw(account string, count int64, head BlockHash) (history []AccountHistoryRaw, previous BlockHash, err error) {
+	history, previous, _, err = c.AccountHistoryRawWithOptions(account, count, AccountHistoryOptions{Head: head})
+	return
+}
+
+// AccountHistoryOptions holds the optional parameters of account_history.
+type AccountHistoryOptions struct {
+	// Head is the block to start from instead of the frontier.
+	Head BlockHash
+	// Offset skips that many blocks from the start.
+	Offset int64
+	// Reverse lists blocks from the open block up to the frontier.
+	Reverse bool
+}
+
+func (opts AccountHistoryOptions) body(account string, count int64, raw bool) map[string]interface{} {
 	body := map[string]interface{}{"action": "account_history", "account": account, "count": count}
-	if head != nil {
-		body["head"] = head
+	if raw {
+		body["raw"] = true
 	}
-	resp, err := c.send(body)
+	if opts.Head != nil {
+		body["head"] = opts.Head
+	}
+	if opts.Offset != 0 {
+		body["offset"] = opts.Offset
+	}
+	if opts.Reverse {
+		body["reverse"] = true
+	}
+	return body
+}
+
+// AccountHistoryWithOptions reports send/receive information for an account.
+// To page through the history, pass previous (or next, when listing in
+// reverse) as the head of the following call.
+func (c *Client) AccountHistoryWithOptions(account string, count int64, opts AccountHistoryOptions) (
+	history []AccountHistory, previous, next BlockHash, err error,
+) {
+	resp, err := c.send(opts.body(account, count, false))
 	if err != nil {
 		return
 	}
 	var v struct {
-		History  []AccountHistory
-		Previous BlockHash
+		// history may come as an empty string instead of an array
+		History        json.RawMessage
+		Previous, Next BlockHash
 	}
-	err = json.Unmarshal(resp, &v)
-	return v.History, v.Previous, err
+	if err = json.Unmarshal(resp, &v); err != nil || string(v.History) == `""` {
+		return nil, v.Previous, v.Next, err
+	}
+	err = json.Unmarshal(v.History, &history)
+	return history, v.Previous, v.Next, err
 }
 
-// AccountHistoryRaw reports all parameters of the block itself as seen in
-// BlockCreate or other APIs returning blocks.
-func (c *Client) AccountHistoryRaw(account string, count int64, head BlockHash) (history []AccountHistoryRaw, previous BlockHash, err error) {
-	body := map[string]interface{}{"action": "account_history", "account": account, "count": count, "raw": true}
-	if head != nil {
-		body["head"] = head
-	}
-	resp, err := c.send(body)
+// AccountHistoryRawWithOptions is like AccountHistoryWithOptions, but reports
+// all parameters of the blocks.
+func (c *Client) AccountHistoryRawWithOptions(account string, count int64, opts AccountHistoryOptions) (
+	history []AccountHistoryRaw, previous, next BlockHash, err error,
+) {
+	resp, err := c.send(opts.body(account, count, true))
 	if err != nil {
 		return
 	}
 	var v struct {
-		History  []AccountHistoryRaw
-		Previous BlockHash
+		// history may come as an empty string instead of an array
+		History        json.RawMessage
+		Previous, Next BlockHash
 	}
-	err = json.Unmarshal(resp, &v)
-	return v.History, v.Previous, err
+	if err = json.Unmarshal(resp, &v); err != nil || string(v.History) == `""` {
+		return nil, v.Previous, v.Next, err
+	}
+	err = json.Unmarshal(v.History, &history)
+	return history, v.Previous, v.Next, err
 }
 
 // AccountInfo returns frontier, open block, change representative block,
 // balance, last modified timestamp from local database & block count for
-// account.
+// account. The confirmed fields are only returned by nodes that support
+// include_confirmed (V22.0+).
 func (c *Client) AccountInfo(account string) (info AccountInfo, err error) {
+	return c.AccountInfoWithOptions(account, AccountInfoOptions{
+		Representative:   true,
+		Weight:           true,
+		Pending:          true,
+		IncludeConfirmed: true,
+	})
+}
+
+// AccountInfoOptions selects the optional fields returned by account_info.
+// AccountInfo selects them all, so that the representative and weight of an
+// account come in the same round trip as its frontier and balance, making
+// AccountRepresentative and AccountWeight unnecessary.
+type AccountInfoOptions struct {
+	// Representative returns AccountInfo.Representative.
+	Representative bool
+	// Weight returns AccountInfo.Weight.
+	Weight bool
+	// Pending returns AccountInfo.Pending.
+	Pending bool
+	// IncludeConfirmed returns the Confirmed fields of AccountInfo.
+	IncludeConfirmed bool
+}
+
+// AccountInfoWithOptions is like AccountInfo, but only requests the optional
+// fields selected in opts.
+func (c *Client) AccountInfoWithOptions(account string, opts AccountInfoOptions) (info AccountInfo, err error) {
 	resp, err := c.send(map[string]interface{}{
-		"action":         "account_info",
-		"account":        account,
-		"representative": true,
-		"weight":         true,
-		"pending":        true,
+		"action":            "account_info",
+		"account":           account,
+		"representative":    opts.Representative,
+		"weight":            opts.Weight,
+		"pending":           opts.Pending,
+		"include_confirmed": opts.IncludeConfirmed,
 	})
 	if err != nil {
 		return
@@ -84,6 +185,79 @@ func (c *Client) AccountInfo(account string) (info AccountInfo, err error) {
 	return
 }
 
+// AccountExists reports whether account has been opened, i.e. has at least
+// one block. The "Account not found" error of the node is not an error here,
+// but means the account is unopened.
+func (c *Client) AccountExists(account string) (exists bool, err error) {
+	_, err = c.AccountInfoWithOptions(account, AccountInfoOptions{})
+	switch {
+	case err == nil:
+		exists = true
+	case errors.Is(err, ErrAccountNotFound):
+		err = nil
+	}
+	return
+}
+
+// AccountOpenBlock returns the open block of account.
+func (c *Client) AccountOpenBlock(account string) (open *BlockInfo, err error) {
+	info, err := c.AccountInfoWithOptions(account, AccountInfoOptions{})
+	if err != nil {
+		return
+	}
+	block, err := c.BlockInfo(info.OpenBlock)
+	if err != nil {
+		return
+	}
+	return &block, nil
+}
+
+// VerifyOpenBlock checks that open is the first block of its account and
+// that it receives the whole amount of a send to that account, which is
+// returned.
+func (c *Client) VerifyOpenBlock(open *BlockInfo) (send *BlockInfo, err error) {
+	if open.Contents == nil {
+		return nil, errors.New("open block has no contents")
+	}
+	if new(big.Int).SetBytes(open.Contents.Previous).Sign() != 0 {
+		return nil, errors.New("open block has a previous block")
+	}
+	source := open.Contents.Link
+	if open.Contents.Type == "open" {
+		source = open.Contents.Source
+	}
+	block, err := c.BlockInfo(source)
+	if err != nil {
+		return
+	}
+	if block.Contents == nil {
+		return nil, errors.New("source block has no contents")
+	}
+	var destination []byte
+	switch {
+	case block.Contents.Type == "send":
+		destination, err = util.AddressToPubkey(block.Contents.Destination)
+	case block.Subtype == "send":
+		destination = block.Contents.Link
+	default:
+		return nil, errors.New("source block is not a send")
+	}
+	if err != nil {
+		return
+	}
+	account, err := util.AddressToPubkey(open.BlockAccount)
+	if err != nil {
+		return
+	}
+	if !bytes.Equal(destination, account) {
+		return nil, errors.New("source block sends to a different account")
+	}
+	if block.Amount == nil || open.Amount == nil || block.Amount.Cmp(&open.Amount.Int) != 0 {
+		return nil, errors.New("open block amount does not match the send")
+	}
+	return &block, nil
+}
+
 // AccountRepresentative returns the representative for account.
 func (c *Client) AccountRepresentative(account string) (representative string, err error) {
 	resp, err := c.send(map[string]interface{}{"action": "account_representative", "account": account})
@@ -107,13 +281,34 @@ func (c *Client) AccountWeight(account string) (weight *RawAmount, err error) {
 }
 
 // AccountBalance returns how many RAW is owned and how many have not yet been received.
+// Receivable is the name used by newer nodes for Pending, and both are set
+// whichever of them the node reports.
 type AccountBalance struct {
-	Balance, Pending *RawAmount
+	Balance, Pending, Receivable *RawAmount
+}
+
+// UnmarshalJSON sets *b to a copy of data.
+func (b *AccountBalance) UnmarshalJSON(data []byte) (err error) {
+	type accountBalance AccountBalance
+	if err = json.Unmarshal(data, (*accountBalance)(b)); err != nil {
+		return
+	}
+	if b.Pending == nil {
+		b.Pending = b.Receivable
+	} else if b.Receivable == nil {
+		b.Receivable = b.Pending
+	}
+	return
 }
 
 // AccountsBalances returns how many RAW is owned and how many have not yet been received by accounts list.
 func (c *Client) AccountsBalances(accounts []string) (balances map[string]*AccountBalance, err error) {
-	resp, err := c.send(map[string]interface{}{"action": "accounts_balances", "accounts": accounts})
+	return c.AccountsBalancesWithOptions(accounts, AccountBalanceOptions{})
+}
+
+// AccountsBalancesWithOptions is like AccountsBalances, with the options in opts.
+func (c *Client) AccountsBalancesWithOptions(accounts []string, opts AccountBalanceOptions) (balances map[string]*AccountBalance, err error) {
+	resp, err := c.send(opts.body(map[string]interface{}{"action": "accounts_balances", "accounts": accounts}))
 	if err != nil {
 		return
 	}
@@ -158,17 +353,102 @@ func (h *HashToPendingMap) UnmarshalJSON(data []byte) (err error) {
 	return
 }
 
+// Sorted returns the hashes of the pending blocks by amount in descending
+// order, which is the order the node sorts them in. Blocks of equal amount
+// are ordered by hash.
+func (h HashToPendingMap) Sorted() (hashes []string) {
+	hashes = make([]string, 0, len(h))
+	for hash := range h {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		switch h[hashes[i]].Amount.Cmp(h[hashes[j]].Amount.bigInt()) {
+		case 1:
+			return true
+		case -1:
+			return false
+		}
+		return hashes[i] < hashes[j]
+	})
+	return
+}
+
+// AccountReceivable returns the pending blocks of a single account, up to
+// count of them, or all with a count of -1. Only blocks of at least
+// threshold are returned if it isn't nil. The source of each block is only
+// set if source is true, while the amount is always set.
+func (c *Client) AccountReceivable(account string, count int64, threshold *RawAmount, source bool) (pending HashToPendingMap, err error) {
+	if threshold == nil || threshold.Sign() == 0 {
+		// A zero threshold without source makes the node list hashes
+		// alone, while a threshold of 1 raw still returns every block.
+		threshold = &RawAmount{Int: *big.NewInt(1)}
+	}
+	resp, err := c.send(map[string]interface{}{
+		"action":                 "pending",
+		"account":                account,
+		"count":                  count,
+		"threshold":              threshold,
+		"source":                 source,
+		"include_only_confirmed": true,
+	})
+	if err != nil {
+		return
+	}
+	if source {
+		var v struct{ Blocks HashToPendingMap }
+		err = json.Unmarshal(resp, &v)
+		return v.Blocks, err
+	}
+	var v struct {
+		// blocks may come as an empty string instead of an object
+		Blocks json.RawMessage
+	}
+	if err = json.Unmarshal(resp, &v); err != nil || string(v.Blocks) == `""` {
+		return
+	}
+	var amounts map[string]*RawAmount
+	if err = json.Unmarshal(v.Blocks, &amounts); err != nil {
+		return
+	}
+	pending = make(HashToPendingMap, len(amounts))
+	for hash, amount := range amounts {
+		pending[hash] = AccountPending{Amount: amount}
+	}
+	return
+}
+
 // AccountsPending returns a list of pending block hashes with amount and source accounts.
 func (c *Client) AccountsPending(accounts []string, count int64, threshold *RawAmount) (pending map[string]HashToPendingMap, err error) {
+	return c.AccountsPendingWithOptions(accounts, AccountsPendingOptions{Count: count, Threshold: threshold})
+}
+
+// AccountsPendingOptions are the optional parameters of accounts_pending.
+type AccountsPendingOptions struct {
+	// Count limits the number of pending blocks returned for each account,
+	// -1 for no limit.
+	Count     int64
+	Threshold *RawAmount
+	// Sorting asks the node to sort the blocks of each account by amount in
+	// descending order. The order is lost in the returned maps, so use
+	// HashToPendingMap.Sorted to visit the blocks in that order.
+	Sorting bool
+}
+
+// AccountsPendingWithOptions is like AccountsPending but takes optional
+// parameters.
+func (c *Client) AccountsPendingWithOptions(accounts []string, opts AccountsPendingOptions) (pending map[string]HashToPendingMap, err error) {
 	m := map[string]interface{}{
 		"action":                 "accounts_pending",
 		"accounts":               accounts,
-		"count":                  count,
+		"count":                  opts.Count,
 		"include_only_confirmed": true,
 		"source":                 true,
 	}
-	if threshold != nil {
-		m["threshold"] = threshold
+	if opts.Threshold != nil {
+		m["threshold"] = opts.Threshold
+	}
+	if opts.Sorting {
+		m["sorting"] = true
 	}
 	resp, err := c.send(m)
 	if err != nil {
@@ -185,6 +465,84 @@ func (c *Client) AccountsPending(accounts []string, count int64, threshold *RawA
 	return v.Blocks, err
 }
 
+// AccountsPendingCount returns the number of pending blocks of accounts, up
+// to max per account. The node has no way of counting pending blocks, so this
+// requests the pending block hashes alone, which is the cheapest form of
+// accounts_pending, and counts them. Pass a max of -1 for no limit.
+func (c *Client) AccountsPendingCount(accounts []string, max int64) (counts map[string]uint64, err error) {
+	resp, err := c.send(map[string]interface{}{
+		"action":                 "accounts_pending",
+		"accounts":               accounts,
+		"count":                  max,
+		"include_only_confirmed": true,
+	})
+	if err != nil {
+		return
+	}
+	var u struct{ Blocks string }
+	if err = json.Unmarshal(resp, &u); err == nil && u.Blocks == "" {
+		return
+	}
+	var v struct{ Blocks map[string]json.RawMessage }
+	if err = json.Unmarshal(resp, &v); err != nil {
+		return
+	}
+	counts = make(map[string]uint64, len(v.Blocks))
+	for account, blocks := range v.Blocks {
+		// accounts without pending blocks may come as an empty string
+		var hashes []BlockHash
+		_ = json.Unmarshal(blocks, &hashes)
+		counts[account] = uint64(len(hashes))
+	}
+	return
+}
+
+// PendingInfo reports a pending block along with details of the send block.
+type PendingInfo struct {
+	AccountPending
+	LocalTimestamp Uint64
+	Confirmed      bool
+	Contents       *Block
+}
+
+// AccountsPendingInfo is like AccountsPending, but additionally looks up the
+// pending send blocks with a single BlocksInfo call to report when they were
+// created and their contents.
+func (c *Client) AccountsPendingInfo(accounts []string, count int64, threshold *RawAmount) (pending map[string]map[string]PendingInfo, err error) {
+	pendings, err := c.AccountsPending(accounts, count, threshold)
+	if err != nil || len(pendings) == 0 {
+		return
+	}
+	var hashes []BlockHash
+	for _, p := range pendings {
+		for hash := range p {
+			h, err := ParseBlockHash(hash)
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, h)
+		}
+	}
+	blocks, err := c.BlocksInfo(hashes)
+	if err != nil {
+		return
+	}
+	pending = make(map[string]map[string]PendingInfo, len(pendings))
+	for account, p := range pendings {
+		pending[account] = make(map[string]PendingInfo, len(p))
+		for hash, ap := range p {
+			info := PendingInfo{AccountPending: ap}
+			if b := blocks[hash]; b != nil {
+				info.LocalTimestamp = b.LocalTimestamp
+				info.Confirmed = b.Confirmed
+				info.Contents = b.Contents
+			}
+			pending[account][hash] = info
+		}
+	}
+	return
+}
+
 // Delegators returns a list of pairs of delegator names given a representative account
 // and its balance.
 func (c *Client) Delegators(account string) (delegators map[string]*RawAmount, err error) {
@@ -204,10 +562,10 @@ func (c *Client) DelegatorsCount(account string) (count uint64, err error) {
 		return
 	}
 	var v struct {
-		Count uint64 `json:",string"`
+		Count Uint64
 	}
 	err = json.Unmarshal(resp, &v)
-	return v.Count, err
+	return uint64(v.Count), err
 }
 
 // FrontierCount reports the number of accounts in the ledger.
@@ -217,10 +575,10 @@ func (c *Client) FrontierCount() (count uint64, err error) {
 		return
 	}
 	var v struct {
-		Count uint64 `json:",string"`
+		Count Uint64
 	}
 	err = json.Unmarshal(resp, &v)
-	return v.Count, err
+	return uint64(v.Count), err
 }
 
 // Frontiers returns a list of pairs of account and block hash representing the
@@ -285,3 +643,27 @@ func (c *Client) RepresentativesOnline() (representatives map[string]Representat
 	err = json.Unmarshal(resp, &v)
 	return v.Representatives, err
 }
+
+// Unopened returns the total pending balance for unopened accounts in the
+// local database, starting at account (optional) up to count (optional, no
+// limit if 0 or less), sorted in ascending lexicographic order.
+func (c *Client) Unopened(account string, count int64) (accounts map[string]*RawAmount, err error) {
+	body := map[string]interface{}{"action": "unopened"}
+	if account != "" {
+		body["account"] = account
+	}
+	if count > 0 {
+		body["count"] = count
+	}
+	resp, err := c.send(body)
+	if err != nil {
+		return
+	}
+	var u struct{ Accounts string }
+	if err = json.Unmarshal(resp, &u); err == nil && u.Accounts == "" {
+		return
+	}
+	var v struct{ Accounts map[string]*RawAmount }
+	err = json.Unmarshal(resp, &v)
+	return v.Accounts, err
+}
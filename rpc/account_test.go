@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,8 +43,8 @@ func TestAccountHistory(t *testing.T) {
 	assert.Equal(t, "receive", h.Type)
 	assert.Equal(t, testAccount, h.Account)
 	assertEqualBig(t, "100000000000000000000000000", &h.Amount.Int)
-	assert.Equal(t, uint64(1604610080), h.LocalTimestamp)
-	assert.Equal(t, uint64(3), h.Height)
+	assert.Equal(t, rpc.Uint64(1604610080), h.LocalTimestamp)
+	assert.Equal(t, rpc.Uint64(3), h.Height)
 	assertEqualBytes(t, "8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD", h.Hash)
 	assertEqualBytes(t, "CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E", previous)
 }
@@ -61,8 +62,8 @@ func TestAccountHistoryRaw(t *testing.T) {
 	assert.Equal(t, "receive", h.Subtype)
 	assert.Equal(t, testAccount, h.Account)
 	assertEqualBig(t, "100000000000000000000000000", &h.Amount.Int)
-	assert.Equal(t, uint64(1604610080), h.LocalTimestamp)
-	assert.Equal(t, uint64(3), h.Height)
+	assert.Equal(t, rpc.Uint64(1604610080), h.LocalTimestamp)
+	assert.Equal(t, rpc.Uint64(3), h.Height)
 	assertEqualBytes(t, "8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD", h.Hash)
 	assertEqualBytes(t, "788f7ec074f1854b", h.Work)
 	assertEqualBytes(t, "E0F2C0187F87917C28BB989DA516114F64FEEAD307011F73F1A0982B3603A51740279ED5DA4D428C3F0E652A638BB75F790B695F9D23125B54DB3312A7F28100", h.Signature)
@@ -76,10 +77,10 @@ func TestAccountInfo(t *testing.T) {
 	assertEqualBytes(t, "E6F513D4821F60151DD3C08C078AF3403F59AE44CC7983083E2391A3E1972A8F", i.OpenBlock)
 	assertEqualBytes(t, "8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD", i.RepresentativeBlock)
 	assertEqualBig(t, "134000000000000000000000000", &i.Balance.Int)
-	assert.Equal(t, uint64(1604610080), i.ModifiedTimestamp)
-	assert.Equal(t, uint64(3), i.BlockCount)
-	assert.Equal(t, uint64(2), i.AccountVersion)
-	assert.Equal(t, uint64(3), i.ConfirmationHeight)
+	assert.Equal(t, rpc.Uint64(1604610080), i.ModifiedTimestamp)
+	assert.Equal(t, rpc.Uint64(3), i.BlockCount)
+	assert.Equal(t, rpc.Uint64(2), i.AccountVersion)
+	assert.Equal(t, rpc.Uint64(3), i.ConfirmationHeight)
 	assertEqualBytes(t, "8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD", i.ConfirmationHeightFrontier)
 }
 
@@ -145,3 +146,212 @@ func TestRepresentativesOnline(t *testing.T) {
 		assert.Greater(t, r.Weight.Sign(), 0)
 	}
 }
+
+func TestAccountInfoWithOptions(t *testing.T) {
+	c, body := newRecordingClient(t, `{"frontier":"`+testBlockInfoHash+`","balance":"10",`+
+		`"confirmed_balance":"5","confirmed_height":"2","confirmed_frontier":"`+testBlockInfoHash+`"}`)
+	i, err := c.AccountInfoWithOptions(testAccount, rpc.AccountInfoOptions{IncludeConfirmed: true})
+	require.Nil(t, err)
+	assert.Equal(t, true, body()["include_confirmed"])
+	assert.Equal(t, false, body()["representative"])
+	assertEqualBig(t, "10", &i.Balance.Int)
+	assertEqualBig(t, "5", &i.ConfirmedBalance.Int)
+	assert.Equal(t, rpc.Uint64(2), i.ConfirmedHeight)
+	assertEqualBytes(t, testBlockInfoHash, i.ConfirmedFrontier)
+}
+
+func TestAccountInfoRepresentativeAndWeight(t *testing.T) {
+	c, body := newRecordingClient(t, `{"frontier":"`+testBlockInfoHash+`","balance":"10",`+
+		`"representative":"`+testAccount+`","weight":"20","pending":"3"}`)
+	i, err := c.AccountInfo(testAccount)
+	require.Nil(t, err)
+	for _, option := range []string{"representative", "weight", "pending", "include_confirmed"} {
+		assert.Equal(t, true, body()[option], option)
+	}
+	assert.Equal(t, testAccount, i.Representative)
+	assertEqualBig(t, "20", &i.Weight.Int)
+	assertEqualBig(t, "3", &i.Pending.Int)
+}
+
+func TestAccountsPendingInfo(t *testing.T) {
+	c := newActionClient(t, map[string]string{
+		"accounts_pending": `{"blocks":{"` + testAccount + `":{"` + testBlockInfoHash +
+			`":{"amount":"100","source":"nano_3kwppxjcggzs65fjh771ch6dbuic3xthsn5wsg6i5537jacw7m493ra8574x"}}}}`,
+		"blocks_info": `{"blocks":{"` + testBlockInfoHash + `":{"local_timestamp":"1604610080","confirmed":"true",` +
+			`"contents":{"type":"state","account":"nano_3kwppxjcggzs65fjh771ch6dbuic3xthsn5wsg6i5537jacw7m493ra8574x"}}}}`,
+	})
+	pending, err := c.AccountsPendingInfo([]string{testAccount}, -1, nil)
+	require.Nil(t, err)
+	p := pending[testAccount][testBlockInfoHash]
+	assertEqualBig(t, "100", &p.Amount.Int)
+	assert.Equal(t, "nano_3kwppxjcggzs65fjh771ch6dbuic3xthsn5wsg6i5537jacw7m493ra8574x", p.Source)
+	assert.Equal(t, rpc.Uint64(1604610080), p.LocalTimestamp)
+	assert.True(t, p.Confirmed)
+	assert.Equal(t, p.Source, p.Contents.Account)
+}
+
+func TestAccountHistoryWithOptions(t *testing.T) {
+	c, body := newRecordingClient(t, `{"account":"`+testAccount+`","history":[{"type":"receive",`+
+		`"account":"nano_3kwppxjcggzs65fjh771ch6dbuic3xthsn5wsg6i5537jacw7m493ra8574x","amount":"100",`+
+		`"local_timestamp":"1604610080","height":"1","hash":"`+testBlockInfoHash+`"}],"next":"`+testBlockInfoHash+`"}`)
+	history, previous, next, err := c.AccountHistoryWithOptions(testAccount, 1, rpc.AccountHistoryOptions{
+		Offset: 2, Reverse: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "receive", history[0].Type)
+	assertEqualBig(t, "100", &history[0].Amount.Int)
+	assert.Nil(t, previous)
+	assertEqualBytes(t, testBlockInfoHash, next)
+	assert.Equal(t, float64(2), body()["offset"])
+	assert.Equal(t, true, body()["reverse"])
+	assert.NotContains(t, body(), "head")
+	assert.NotContains(t, body(), "raw")
+
+	c = newTestClient(t, `{"account":"`+testAccount+`","history":""}`)
+	raw, _, _, err := c.AccountHistoryRawWithOptions(testAccount, 1, rpc.AccountHistoryOptions{})
+	require.Nil(t, err)
+	assert.Empty(t, raw)
+}
+
+func TestAccountsPendingCount(t *testing.T) {
+	other := "nano_3kwppxjcggzs65fjh771ch6dbuic3xthsn5wsg6i5537jacw7m493ra8574x"
+	c, body := newRecordingClient(t, `{"blocks":{"`+testAccount+`":["`+testBlockInfoHash+`","`+
+		`CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E"],"`+other+`":""}}`)
+	counts, err := c.AccountsPendingCount([]string{testAccount, other}, 100)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]uint64{testAccount: 2, other: 0}, counts)
+	assert.NotContains(t, body(), "source")
+	assert.Equal(t, float64(100), body()["count"])
+
+	counts, err = newTestClient(t, `{"blocks":""}`).AccountsPendingCount([]string{testAccount}, -1)
+	require.Nil(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestVerifyOpenBlock(t *testing.T) {
+	pubkey, err := util.AddressToPubkey(testAccount)
+	require.Nil(t, err)
+	link, err := rpc.ParseBlockHash(testBlockInfoHash)
+	require.Nil(t, err)
+	open := &rpc.BlockInfo{
+		BlockAccount: testAccount,
+		Amount:       &rpc.RawAmount{Int: *big.NewInt(100)},
+		Contents:     &rpc.Block{Type: "state", Previous: make(rpc.BlockHash, 32), Link: link},
+	}
+	send := func(to []byte, amount string) string {
+		return `{"amount":"` + amount + `","subtype":"send","contents":{"type":"state","link":"` +
+			hex.EncodeToString(to) + `"}}`
+	}
+	c := newActionClient(t, map[string]string{"block_info": send(pubkey, "100")})
+	block, err := c.VerifyOpenBlock(open)
+	require.Nil(t, err)
+	assert.Equal(t, "100", block.Amount.String())
+
+	_, err = newActionClient(t, map[string]string{"block_info": send(pubkey, "99")}).VerifyOpenBlock(open)
+	assert.NotNil(t, err)
+	_, err = newActionClient(t, map[string]string{"block_info": send(make([]byte, 32), "100")}).VerifyOpenBlock(open)
+	assert.NotNil(t, err)
+	open.Contents.Previous = link
+	_, err = c.VerifyOpenBlock(open)
+	assert.NotNil(t, err)
+}
+
+func TestUnopened(t *testing.T) {
+	c, body := newRecordingClient(t, `{"accounts":{"`+testAccount+`":"207034077034226183413773082289554618448"}}`)
+	accounts, err := c.Unopened(testAccount, 1)
+	require.Nil(t, err)
+	assert.Equal(t, "207034077034226183413773082289554618448", accounts[testAccount].String())
+	assert.Equal(t, testAccount, body()["account"])
+	assert.Equal(t, 1.0, body()["count"])
+
+	c, body = newRecordingClient(t, `{"accounts":""}`)
+	accounts, err = c.Unopened("", 0)
+	require.Nil(t, err)
+	assert.Empty(t, accounts)
+	assert.Nil(t, body()["account"])
+	assert.Nil(t, body()["count"])
+}
+
+func TestAccountBalanceWithOptions(t *testing.T) {
+	c, body := newRecordingClient(t, `{"balance":"10","receivable":"5"}`)
+	balance, pending, err := c.AccountBalance(testAccount)
+	require.Nil(t, err)
+	assert.Equal(t, "10", balance.String())
+	assert.Equal(t, "5", pending.String())
+	assert.Nil(t, body()["include_only_confirmed"])
+
+	b, err := c.AccountBalanceWithOptions(testAccount, rpc.AccountBalanceOptions{IncludeOnlyConfirmed: true})
+	require.Nil(t, err)
+	assert.Equal(t, "5", b.Receivable.String())
+	assert.Equal(t, true, body()["include_only_confirmed"])
+
+	c, body = newRecordingClient(t, `{"balances":{"`+testAccount+`":{"balance":"10","pending":"5"}}}`)
+	balances, err := c.AccountsBalancesWithOptions([]string{testAccount}, rpc.AccountBalanceOptions{IncludeOnlyConfirmed: true})
+	require.Nil(t, err)
+	assert.Equal(t, "5", balances[testAccount].Pending.String())
+	assert.Equal(t, "5", balances[testAccount].Receivable.String())
+	assert.Equal(t, true, body()["include_only_confirmed"])
+}
+
+func TestAccountsPendingWithOptions(t *testing.T) {
+	c, body := newRecordingClient(t, `{"blocks":{"`+testAccount+`":{`+
+		`"B2":{"amount":"5"},"A1":{"amount":"5"},"C3":{"amount":"10"},"D4":{"amount":"1"}}}}`)
+	pendings, err := c.AccountsPendingWithOptions([]string{testAccount}, rpc.AccountsPendingOptions{Count: 4, Sorting: true})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"C3", "A1", "B2", "D4"}, pendings[testAccount].Sorted())
+	assert.Equal(t, true, body()["sorting"])
+	assert.Equal(t, 4.0, body()["count"])
+
+	_, err = c.AccountsPending([]string{testAccount}, -1, nil)
+	require.Nil(t, err)
+	assert.Nil(t, body()["sorting"])
+	assert.Nil(t, body()["threshold"])
+}
+
+func TestAccountExists(t *testing.T) {
+	c, body := newRecordingClient(t, `{"frontier":"`+testBlockInfoHash+`"}`)
+	exists, err := c.AccountExists(testAccount)
+	require.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "account_info", body()["action"])
+
+	exists, err = newTestClient(t, `{"error":"Account not found"}`).AccountExists(testAccount)
+	require.Nil(t, err)
+	assert.False(t, exists)
+
+	_, err = newTestClient(t, `{"error":"Bad account number"}`).AccountExists("nano_invalid")
+	assert.NotNil(t, err)
+}
+
+func TestAccountReceivable(t *testing.T) {
+	source := "nano_3kwppxjcggzs65fjh771ch6dbuic3xthsn5wsg6i5537jacw7m493ra8574x"
+	c, body := newRecordingClient(t, `{"blocks":{"`+testBlockInfoHash+`":{"amount":"100","source":"`+source+`"}}}`)
+	pending, err := c.AccountReceivable(testAccount, 5, &rpc.RawAmount{Int: *big.NewInt(10)}, true)
+	require.Nil(t, err)
+	assertEqualBig(t, "100", &pending[testBlockInfoHash].Amount.Int)
+	assert.Equal(t, source, pending[testBlockInfoHash].Source)
+	assert.Equal(t, "pending", body()["action"])
+	assert.Equal(t, testAccount, body()["account"])
+	assert.Equal(t, "10", body()["threshold"])
+	assert.Equal(t, true, body()["source"])
+
+	c, body = newRecordingClient(t, `{"blocks":{"`+testBlockInfoHash+`":"100"}}`)
+	pending, err = c.AccountReceivable(testAccount, -1, nil, false)
+	require.Nil(t, err)
+	assertEqualBig(t, "100", &pending[testBlockInfoHash].Amount.Int)
+	assert.Equal(t, "", pending[testBlockInfoHash].Source)
+	assert.Equal(t, "1", body()["threshold"])
+
+	// The node's response to a zero threshold without source has no amounts
+	// to report, which is an error rather than an empty result.
+	_, err = newTestClient(t, `{"blocks":["`+testBlockInfoHash+`"]}`).AccountReceivable(testAccount, -1, nil, false)
+	assert.NotNil(t, err)
+
+	pending, err = newTestClient(t, `{"blocks":""}`).AccountReceivable(testAccount, -1, nil, false)
+	require.Nil(t, err)
+	assert.Empty(t, pending)
+	pending, err = newTestClient(t, `{"blocks":""}`).AccountReceivable(testAccount, -1, nil, true)
+	require.Nil(t, err)
+	assert.Empty(t, pending)
+}
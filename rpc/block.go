@@ -1,7 +1,12 @@
 package rpc
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 )
 
 // BlockAccount returns the account containing block.
@@ -22,7 +27,7 @@ func (c *Client) BlockConfirm(hash BlockHash) (started bool, err error) {
 		return
 	}
 	var v struct {
-		Started int `json:",string"`
+		Started Uint64
 	}
 	err = json.Unmarshal(resp, &v)
 	return v.Started == 1, err
@@ -35,10 +40,21 @@ func (c *Client) BlockCount() (cemented, count, unchecked uint64, err error) {
 		return
 	}
 	var v struct {
-		Cemented, Count, Unchecked uint64 `json:",string"`
+		Cemented, Count, Unchecked Uint64
 	}
 	err = json.Unmarshal(resp, &v)
-	return v.Cemented, v.Count, v.Unchecked, err
+	return uint64(v.Cemented), uint64(v.Count), uint64(v.Unchecked), err
+}
+
+// BlockCountFull reports the block counts of the ledger, including any
+// counters not returned by BlockCount.
+func (c *Client) BlockCountFull() (count BlockCount, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "block_count"})
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(resp, &count)
+	return
 }
 
 // BlockInfo retrieves a json representation of a block.
@@ -64,7 +80,34 @@ func (c *Client) Blocks(hashes []BlockHash) (blocks map[string]*Block, err error
 
 // BlocksInfo retrieves a json representations of blocks in contents.
 func (c *Client) BlocksInfo(hashes []BlockHash) (blocks map[string]*BlockInfo, err error) {
-	resp, err := c.send(map[string]interface{}{"action": "blocks_info", "json_block": true, "hashes": hashes})
+	return c.BlocksInfoWithOptions(hashes, BlocksInfoOptions{})
+}
+
+// BlocksInfoOptions are the options for BlocksInfoWithOptions.
+type BlocksInfoOptions struct {
+	// Pending sets BlockInfo.Pending for sends.
+	Pending bool
+	// Source sets BlockInfo.SourceAccount for receives.
+	Source bool
+	// Balance asks for the balance of the account as of each block, which
+	// recent nodes include regardless.
+	Balance bool
+}
+
+// BlocksInfoWithOptions is BlocksInfo with extra information about each
+// block, as selected by opts.
+func (c *Client) BlocksInfoWithOptions(hashes []BlockHash, opts BlocksInfoOptions) (blocks map[string]*BlockInfo, err error) {
+	body := map[string]interface{}{"action": "blocks_info", "json_block": true, "hashes": hashes}
+	if opts.Pending {
+		body["pending"] = true
+	}
+	if opts.Source {
+		body["source"] = true
+	}
+	if opts.Balance {
+		body["balance"] = true
+	}
+	resp, err := c.send(body)
 	if err != nil {
 		return
 	}
@@ -99,6 +142,62 @@ func (c *Client) BlocksInfoIncludingNotFound(hashes []BlockHash) (blocks map[str
 	return v.Blocks, blocksNotFound, err
 }
 
+// blocksInfoConcurrency bounds the number of concurrent requests made by
+// BlocksInfoChunked.
+const blocksInfoConcurrency = 4
+
+// BlocksInfoChunked is like BlocksInfoIncludingNotFound, but splits hashes
+// into chunks of at most chunkSize hashes which are requested concurrently.
+// The first error stops any further chunks from being requested.
+func (c *Client) BlocksInfoChunked(hashes []BlockHash, chunkSize int) (blocks map[string]*BlockInfo, notFound []BlockHash, err error) {
+	if chunkSize <= 0 {
+		return nil, nil, errors.New("chunk size must be positive")
+	}
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		sem   = make(chan struct{}, blocksInfoConcurrency)
+	)
+	blocks = make(map[string]*BlockInfo, len(hashes))
+	for i := 0; i < len(hashes); i += chunkSize {
+		j := i + chunkSize
+		if j > len(hashes) {
+			j = len(hashes)
+		}
+		sem <- struct{}{}
+		// No more chunks are requested once one has failed.
+		mutex.Lock()
+		failed := err != nil
+		mutex.Unlock()
+		if failed {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(chunk []BlockHash) {
+			defer func() { <-sem; wg.Done() }()
+			b, nf, err2 := c.BlocksInfoIncludingNotFound(chunk)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err2 != nil {
+				if err == nil {
+					err = err2
+				}
+				return
+			}
+			for hash, info := range b {
+				blocks[hash] = info
+			}
+			notFound = append(notFound, nf...)
+		}(hashes[i:j])
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, nil, err
+	}
+	return
+}
+
 // Chain returns a consecutive list of block hashes in the account chain starting
 // at block back to count (direction from frontier back to open block, from newer
 // blocks to older). Will list all blocks back to the open block of this chain when
@@ -113,23 +212,177 @@ func (c *Client) Chain(block BlockHash, count int64) (blocks []BlockHash, err er
 	return v.Blocks, err
 }
 
-// Process publishes block to the network.
-func (c *Client) Process(block *Block, subtype string) (hash BlockHash, err error) {
-	resp, err := c.send(map[string]interface{}{
+// ChainIterator calls fn for each block hash in the account chain starting
+// at block back to the open block, like Chain with a count of -1, but fetches
+// the hashes pageSize at a time. Iteration stops at the first error returned
+// by fn or when ctx is done, and that error is returned.
+func (c *Client) ChainIterator(ctx context.Context, block BlockHash, pageSize int64, fn func(BlockHash) error) error {
+	return c.iterate(ctx, block, pageSize, fn, (*Client).Chain)
+}
+
+// SuccessorsIterator calls fn for each block hash in the account chain
+// starting at block up to the frontier, like Successors with a count of -1,
+// but fetches the hashes pageSize at a time. Iteration stops at the first
+// error returned by fn or when ctx is done, and that error is returned.
+func (c *Client) SuccessorsIterator(ctx context.Context, block BlockHash, pageSize int64, fn func(BlockHash) error) error {
+	return c.iterate(ctx, block, pageSize, fn, (*Client).Successors)
+}
+
+func (c *Client) iterate(
+	ctx context.Context, block BlockHash, pageSize int64, fn func(BlockHash) error,
+	page func(*Client, BlockHash, int64) ([]BlockHash, error),
+) error {
+	if pageSize <= 0 {
+		return errors.New("page size must be positive")
+	}
+	c2 := *c
+	c2.Ctx = ctx
+	blocks, err := page(&c2, block, pageSize)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		for _, hash := range blocks {
+			if err = fn(hash); err != nil {
+				return err
+			}
+		}
+		if int64(len(blocks)) < pageSize {
+			return nil
+		}
+		// Each page after the first starts with the last hash of the previous one.
+		block = blocks[len(blocks)-1]
+		if blocks, err = page(&c2, block, pageSize+1); err == nil && len(blocks) > 0 {
+			blocks = blocks[1:]
+		}
+	}
+}
+
+// Process publishes block to the network. Constants such as SubtypeSend, or
+// untyped strings like "send", can be passed as subtype directly, while
+// string variables need ProcessString, as Process used to take a string.
+func (c *Client) Process(block *Block, subtype BlockSubtype) (hash BlockHash, err error) {
+	return c.ProcessWithOptions(block, subtype, ProcessOptions{})
+}
+
+// ProcessString is like Process, but takes the subtype as a string, which
+// is checked with ParseBlockSubtype before anything is sent to the node.
+func (c *Client) ProcessString(block *Block, subtype string) (hash BlockHash, err error) {
+	st, err := ParseBlockSubtype(subtype)
+	if err != nil {
+		return
+	}
+	return c.Process(block, st)
+}
+
+// ProcessAsync is like Process, but the node queues the block and returns
+// without waiting for it to be validated. The returned hash is computed
+// locally, as the node doesn't report it.
+func (c *Client) ProcessAsync(block *Block, subtype BlockSubtype) (hash BlockHash, err error) {
+	return c.ProcessWithOptions(block, subtype, ProcessOptions{Async: true})
+}
+
+// ProcessAuto is like Process, but finds the subtype of block with
+// DetectSubtype.
+func (c *Client) ProcessAuto(block *Block) (hash BlockHash, err error) {
+	subtype, err := c.DetectSubtype(block)
+	if err != nil {
+		return
+	}
+	return c.Process(block, subtype)
+}
+
+// DetectSubtype finds the subtype of block. Legacy and epoch blocks are
+// recognized from the block itself, as are state blocks opening an account.
+// Other state blocks are compared to their previous block, which is looked
+// up on the node: a lower balance is a send, a higher balance with a link is
+// a receive, and an unchanged balance without a link is a change. Any other
+// block is an error.
+func (c *Client) DetectSubtype(block *Block) (subtype BlockSubtype, err error) {
+	if subtype = BlockSubtype(block.Subtype()); subtype != "" {
+		return
+	}
+	if block.Type != "state" {
+		return "", fmt.Errorf("unknown block type %q", block.Type)
+	}
+	zero := make([]byte, 32)
+	if len(block.Previous) == 0 || bytes.Equal(block.Previous, zero) {
+		return SubtypeOpen, nil
+	}
+	previous, err := c.BlockInfo(block.Previous)
+	if err != nil {
+		return
+	}
+	hasLink := len(block.Link) > 0 && !bytes.Equal(block.Link, zero)
+	switch block.Balance.Cmp(&previous.Balance.Int) {
+	case -1:
+		return SubtypeSend, nil
+	case 1:
+		if hasLink {
+			return SubtypeReceive, nil
+		}
+	default:
+		if !hasLink {
+			return SubtypeChange, nil
+		}
+	}
+	return "", errors.New("unable to determine the block subtype")
+}
+
+// ProcessOptions are the options for ProcessWithOptions.
+type ProcessOptions struct {
+	// Async queues the block without waiting for it to be validated.
+	Async bool
+	// Force resolves a fork in favour of the block being processed.
+	Force bool
+	// WatchWork has the node regenerate the work of the block at a higher
+	// difficulty should it not be confirmed quickly.
+	WatchWork bool
+}
+
+// ProcessWithOptions is like Process, with the options in opts.
+func (c *Client) ProcessWithOptions(block *Block, subtype BlockSubtype, opts ProcessOptions) (hash BlockHash, err error) {
+	body := map[string]interface{}{
 		"action":     "process",
 		"json_block": true,
 		"subtype":    subtype,
 		"block":      block,
-	})
+	}
+	if opts.Async {
+		body["async"] = true
+	}
+	if opts.Force {
+		body["force"] = true
+	}
+	if opts.WatchWork {
+		body["watch_work"] = true
+	}
+	resp, err := c.send(body)
 	if err != nil {
 		return
 	}
+	if opts.Async {
+		return block.Hash()
+	}
 	var v struct{ Hash BlockHash }
 	err = json.Unmarshal(resp, &v)
 	return v.Hash, err
 }
 
-// Republish rebroadcasts blocks starting at hash to the network.
+// Republish rebroadcasts blocks starting at hash to the network. count is
+// the number of blocks of the account chain to rebroadcast, from hash towards
+// the frontier. For each receive among them, up to sources blocks of the
+// chain of the pocketed send, ending at the send, are rebroadcast as well,
+// and for each send, up to destinations blocks of the destination chain,
+// starting at the block receiving it. A zero sources or destinations only
+// rebroadcasts the account chain.
+//
+// The node reports the hashes of all the blocks it rebroadcast in a single
+// list, so which of them were found through sources or destinations can't
+// be told apart. RepublishBlock covers the common case of a single block.
 func (c *Client) Republish(hash BlockHash, count, sources, destinations int64) (blocks []BlockHash, err error) {
 	resp, err := c.send(map[string]interface{}{
 		"action":       "republish",
@@ -146,6 +399,12 @@ func (c *Client) Republish(hash BlockHash, count, sources, destinations int64) (
 	return v.Blocks, err
 }
 
+// RepublishBlock rebroadcasts the block with hash alone.
+func (c *Client) RepublishBlock(hash BlockHash) (err error) {
+	_, err = c.Republish(hash, 1, 0, 0)
+	return
+}
+
 // Successors returns a consecutive list of block hashes in the account chain starting
 // at block up to count (direction from open block up to frontier, from older
 // blocks to newer). Will list all blocks up to frontier (latest block) of this chain
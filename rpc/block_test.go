@@ -1,8 +1,17 @@
 package rpc_test
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hectorchu/gonano/rpc"
 	"github.com/stretchr/testify/assert"
@@ -35,8 +44,8 @@ func testBlockInfo(t *testing.T, info *rpc.BlockInfo) {
 	assert.Equal(t, "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny", info.BlockAccount)
 	assertEqualBig(t, "100000000000000000000000000", &info.Amount.Int)
 	assertEqualBig(t, "134000000000000000000000000", &info.Balance.Int)
-	assert.Equal(t, uint64(3), info.Height)
-	assert.Equal(t, uint64(1604610080), info.LocalTimestamp)
+	assert.Equal(t, rpc.Uint64(3), info.Height)
+	assert.Equal(t, rpc.Uint64(1604610080), info.LocalTimestamp)
 	assert.Equal(t, true, info.Confirmed)
 	assert.Equal(t, "state", info.Contents.Type)
 	assert.Equal(t, "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny", info.Contents.Account)
@@ -63,6 +72,29 @@ func TestBlocksInfo(t *testing.T) {
 	testBlockInfo(t, blocks[testBlockInfoHash])
 }
 
+func TestBlocksInfoWithOptions(t *testing.T) {
+	c, body := newRecordingClient(t, `{"blocks":{"`+testBlockInfoHash+`":{"block_account":"`+testAccount+`",`+
+		`"amount":"100","balance":"5","height":"2","subtype":"send","pending":"1","source_account":"0"}}}`)
+	blocks, err := c.BlocksInfoWithOptions([]rpc.BlockHash{hexString(testBlockInfoHash)}, rpc.BlocksInfoOptions{Pending: true, Source: true})
+	require.Nil(t, err)
+	assert.Equal(t, true, body()["pending"])
+	assert.Equal(t, true, body()["source"])
+	assert.Nil(t, body()["balance"])
+	b := blocks[testBlockInfoHash]
+	require.NotNil(t, b)
+	assert.True(t, b.Pending)
+	assert.Empty(t, b.SourceAccount)
+
+	c, _ = newRecordingClient(t, `{"blocks":{"`+testBlockInfoHash+`":{"block_account":"`+testAccount+`",`+
+		`"amount":"100","balance":"105","height":"3","subtype":"receive","pending":"0","source_account":"`+testAccount+`"}}}`)
+	blocks, err = c.BlocksInfoWithOptions([]rpc.BlockHash{hexString(testBlockInfoHash)}, rpc.BlocksInfoOptions{Pending: true, Source: true})
+	require.Nil(t, err)
+	b = blocks[testBlockInfoHash]
+	require.NotNil(t, b)
+	assert.False(t, b.Pending)
+	assert.Equal(t, testAccount, b.SourceAccount)
+}
+
 func TestChain(t *testing.T) {
 	block := hexString("8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD")
 	blocks, err := getClient().Chain(block, -1)
@@ -82,3 +114,227 @@ func TestSuccessors(t *testing.T) {
 	assertEqualBytes(t, "CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E", blocks[1])
 	assertEqualBytes(t, "8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD", blocks[2])
 }
+
+func TestBlocksInfoChunked(t *testing.T) {
+	var hashes []rpc.BlockHash
+	for i := 0; i < 10; i++ {
+		hashes = append(hashes, bytes.Repeat([]byte{byte(i)}, 32))
+	}
+	var mutex sync.Mutex
+	requests, fail := 0, false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v struct{ Hashes []rpc.BlockHash }
+		json.NewDecoder(r.Body).Decode(&v)
+		mutex.Lock()
+		requests++
+		failing := fail
+		mutex.Unlock()
+		if failing {
+			if v.Hashes[0][0] == 0 {
+				w.Write([]byte(`{"error":"Internal server error in RPC"}`))
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		blocks := map[string]*rpc.BlockInfo{}
+		var notFound []rpc.BlockHash
+		for _, h := range v.Hashes {
+			if h[0] == 7 {
+				notFound = append(notFound, h)
+			} else {
+				blocks[h.String()] = &rpc.BlockInfo{Height: rpc.Uint64(h[0])}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"blocks": blocks, "blocks_not_found": notFound})
+	}))
+	defer s.Close()
+	c := &rpc.Client{URL: s.URL}
+
+	blocks, notFound, err := c.BlocksInfoChunked(hashes, 3)
+	require.Nil(t, err)
+	assert.Equal(t, 4, requests)
+	assert.Len(t, blocks, 9)
+	for _, h := range hashes {
+		if h[0] != 7 {
+			assert.Equal(t, rpc.Uint64(h[0]), blocks[h.String()].Height)
+		}
+	}
+	assert.Equal(t, []rpc.BlockHash{hashes[7]}, notFound)
+
+	// Once the first chunk fails, the remaining chunks aren't requested.
+	requests, fail = 0, true
+	_, _, err = c.BlocksInfoChunked(hashes, 1)
+	assert.Equal(t, rpc.Error("Internal server error in RPC"), err)
+	assert.Less(t, requests, len(hashes))
+
+	_, _, err = c.BlocksInfoChunked(hashes, 0)
+	assert.NotNil(t, err)
+}
+
+func TestChainIterator(t *testing.T) {
+	// A fake chain of 10 blocks, where block i's previous is block i-1.
+	var chain []rpc.BlockHash
+	for i := 0; i < 10; i++ {
+		chain = append(chain, bytes.Repeat([]byte{byte(i)}, 32))
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v struct {
+			Action string
+			Block  rpc.BlockHash
+			Count  int
+		}
+		json.NewDecoder(r.Body).Decode(&v)
+		var blocks []rpc.BlockHash
+		for i := int(v.Block[0]); i >= 0 && i < len(chain) && len(blocks) < v.Count; {
+			blocks = append(blocks, chain[i])
+			if v.Action == "chain" {
+				i--
+			} else {
+				i++
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"blocks": blocks})
+	}))
+	defer s.Close()
+	c := &rpc.Client{URL: s.URL}
+
+	for _, pageSize := range []int64{1, 3, 5, 10, 20} {
+		var got []rpc.BlockHash
+		err := c.ChainIterator(context.Background(), chain[9], pageSize, func(hash rpc.BlockHash) error {
+			got = append(got, hash)
+			return nil
+		})
+		require.Nil(t, err)
+		require.Len(t, got, 10)
+		for i := range got {
+			assert.Equal(t, chain[9-i], got[i])
+		}
+
+		got = nil
+		err = c.SuccessorsIterator(context.Background(), chain[2], pageSize, func(hash rpc.BlockHash) error {
+			got = append(got, hash)
+			return nil
+		})
+		require.Nil(t, err)
+		assert.Equal(t, chain[2:], got)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err := c.ChainIterator(context.Background(), chain[9], 2, func(hash rpc.BlockHash) error {
+		if n++; n == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 3, n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = c.ChainIterator(ctx, chain[9], 2, func(rpc.BlockHash) error { return nil })
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBlockCountFull(t *testing.T) {
+	c := newTestClient(t, `{"count":"1000","unchecked":"10","cemented":"990","full":"5","extra":{"a":1}}`)
+	count, err := c.BlockCountFull()
+	require.Nil(t, err)
+	assert.Equal(t, uint64(1000), count.Count)
+	assert.Equal(t, uint64(10), count.Unchecked)
+	assert.Equal(t, uint64(990), count.Cemented)
+	assert.Equal(t, uint64(5), count.Counters["full"])
+	assert.Len(t, count.Counters, 4)
+
+	cemented, total, _, err := c.BlockCount()
+	require.Nil(t, err)
+	assert.Equal(t, uint64(990), cemented)
+	assert.Equal(t, uint64(1000), total)
+}
+
+func TestProcessWithOptions(t *testing.T) {
+	b := &rpc.Block{
+		Type:           "open",
+		Source:         hexString("E89208DD038FBB269987689621D52292AE9C35941A7484756ECCED92A65093BA"),
+		Representative: "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+		Account:        "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+	}
+	c, body := newRecordingClient(t, `{"started":"1"}`)
+	hash, err := c.ProcessAsync(b, "open")
+	require.Nil(t, err)
+	assert.Equal(t, "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948", hash.String())
+	assert.Equal(t, true, body()["async"])
+	assert.Nil(t, body()["force"])
+
+	c, body = newRecordingClient(t, `{"hash":"`+testBlockInfoHash+`"}`)
+	hash, err = c.ProcessWithOptions(b, "open", rpc.ProcessOptions{Force: true, WatchWork: true})
+	require.Nil(t, err)
+	assert.Equal(t, testBlockInfoHash, hash.String())
+	assert.Equal(t, true, body()["force"])
+	assert.Equal(t, true, body()["watch_work"])
+	assert.Nil(t, body()["async"])
+}
+
+func TestProcessString(t *testing.T) {
+	b := &rpc.Block{
+		Type:           "open",
+		Source:         hexString("E89208DD038FBB269987689621D52292AE9C35941A7484756ECCED92A65093BA"),
+		Representative: "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+		Account:        "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+	}
+	c, body := newRecordingClient(t, `{"hash":"`+testBlockInfoHash+`"}`)
+	subtype := "open"
+	hash, err := c.ProcessString(b, subtype)
+	require.Nil(t, err)
+	assert.Equal(t, testBlockInfoHash, hash.String())
+	assert.Equal(t, "open", body()["subtype"])
+
+	_, err = c.ProcessString(b, "bogus")
+	assert.EqualError(t, err, `unknown block subtype "bogus"`)
+}
+
+func TestRepublishBlock(t *testing.T) {
+	c, body := newRecordingClient(t, `{"success":"","blocks":["`+testBlockInfoHash+`"]}`)
+	require.Nil(t, c.RepublishBlock(hexString(testBlockInfoHash)))
+	assert.Equal(t, "republish", body()["action"])
+	assert.Equal(t, testBlockInfoHash, body()["hash"])
+	assert.Equal(t, 1.0, body()["count"])
+	assert.Equal(t, 0.0, body()["sources"])
+	assert.Equal(t, 0.0, body()["destinations"])
+}
+
+func TestProcessAuto(t *testing.T) {
+	previous := hexString(testBlockInfoHash)
+	link := hexString("E89208DD038FBB269987689621D52292AE9C35941A7484756ECCED92A65093BA")
+	balance := func(n int64) *rpc.RawAmount { return &rpc.RawAmount{Int: *big.NewInt(n)} }
+	c := newActionClient(t, map[string]string{
+		"block_info": `{"balance":"1000","subtype":"send","contents":{"type":"state","balance":"1000"}}`,
+		"process":    `{"hash":"` + testBlockInfoHash + `"}`,
+	})
+	for _, tt := range []struct {
+		block    rpc.Block
+		expected rpc.BlockSubtype
+	}{
+		{rpc.Block{Type: "receive", Previous: previous, Source: link}, rpc.SubtypeReceive},
+		{rpc.Block{Type: "state", Previous: make(rpc.BlockHash, 32), Balance: balance(5), Link: link}, rpc.SubtypeOpen},
+		{rpc.Block{Type: "state", Previous: previous, Balance: balance(999), Link: link}, rpc.SubtypeSend},
+		{rpc.Block{Type: "state", Previous: previous, Balance: balance(1001), Link: link}, rpc.SubtypeReceive},
+		{rpc.Block{Type: "state", Previous: previous, Balance: balance(1000)}, rpc.SubtypeChange},
+	} {
+		subtype, err := c.DetectSubtype(&tt.block)
+		require.Nil(t, err, tt.expected)
+		assert.Equal(t, tt.expected, subtype)
+	}
+	for _, b := range []rpc.Block{
+		{Type: "state", Previous: previous, Balance: balance(1001)},
+		{Type: "state", Previous: previous, Balance: balance(1000), Link: link},
+		{Type: "bogus"},
+	} {
+		_, err := c.DetectSubtype(&b)
+		assert.NotNil(t, err)
+	}
+
+	hash, err := c.ProcessAuto(&rpc.Block{Type: "state", Previous: previous, Balance: balance(1)})
+	require.Nil(t, err)
+	assert.Equal(t, testBlockInfoHash, hash.String())
+}
@@ -11,20 +11,47 @@ import (
 
 // Client is used for connecting to http rpc endpoints.
 type Client struct {
-	URL string
+	URL        string
 	AuthHeader string
-	Ctx context.Context
+	Ctx        context.Context
+	// Limiter, if set, is waited on before every request.
+	Limiter Limiter
 }
 
+// Error is an error message returned by the node.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Errors returned by the node that callers may want to handle specifically.
+const (
+	ErrAccountNotFound Error = "Account not found"
+	ErrFork            Error = "Fork"
+	ErrGapPrevious     Error = "Gap previous"
+	ErrOldBlock        Error = "Old block"
+	ErrUnreceivable    Error = "Unreceivable"
+)
+
 func (c *Client) send(body interface{}) (result []byte, err error) {
+	if c.URL == "" {
+		return nil, errors.New("rpc url not set")
+	}
 	var buf bytes.Buffer
 	if err = json.NewEncoder(&buf).Encode(body); err != nil {
 		return
 	}
-	if c.Ctx == nil {
-		c.Ctx = context.Background()
+	ctx := c.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.Limiter != nil {
+		if err = c.Limiter.Wait(ctx); err != nil {
+			return
+		}
 	}
-	req, err := http.NewRequestWithContext(c.Ctx, http.MethodPost, c.URL, &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, &buf)
 	if err != nil {
 		return
 	}
@@ -43,14 +70,25 @@ func (c *Client) send(body interface{}) (result []byte, err error) {
 	if err = resp.Body.Close(); err != nil {
 		return
 	}
+	// The node reports errors in the body, usually with a 200 status, so
+	// check for them before any caller unmarshals the result. The status is
+	// only reported when there is no error in the body, e.g. from a proxy.
 	var v struct{ Error, Message string }
 	if err = json.Unmarshal(buf.Bytes(), &v); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			err = errors.New(resp.Status)
+		}
 		return
 	}
 	if v.Error != "" {
-		err = errors.New(v.Error)
+		err = Error(v.Error)
 	} else if v.Message != "" {
-		err = errors.New(v.Message)
+		err = Error(v.Message)
+	} else if resp.StatusCode != http.StatusOK {
+		err = errors.New(resp.Status)
+	}
+	if err != nil {
+		return
 	}
-	return buf.Bytes(), err
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,114 @@
+package rpc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, response string) *rpc.Client {
+	c, _ := newRecordingClient(t, response)
+	return c
+}
+
+// newRecordingClient returns a client for a server that always responds with
+// response, along with a function returning the last request body received.
+func newRecordingClient(t *testing.T, response string) (c *rpc.Client, body func() map[string]interface{}) {
+	var mutex sync.Mutex
+	var last map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&v)
+		mutex.Lock()
+		last = v
+		mutex.Unlock()
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(s.Close)
+	return &rpc.Client{URL: s.URL}, func() map[string]interface{} {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return last
+	}
+}
+
+// newActionClient returns a client for a server that responds to each
+// action with the corresponding canned response.
+func newActionClient(t *testing.T, responses map[string]string) *rpc.Client {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v struct{ Action string }
+		json.NewDecoder(r.Body).Decode(&v)
+		resp, ok := responses[v.Action]
+		if !ok {
+			resp = `{"error":"Unknown command"}`
+		}
+		io.WriteString(w, resp)
+	}))
+	t.Cleanup(s.Close)
+	return &rpc.Client{URL: s.URL}
+}
+
+func TestClientError(t *testing.T) {
+	_, err := newTestClient(t, `{"error":"Account not found"}`).AccountInfo(testAccount)
+	assert.True(t, errors.Is(err, rpc.ErrAccountNotFound))
+	_, err = newTestClient(t, `{"error":"Bad account number"}`).AccountInfo(testAccount)
+	assert.Equal(t, rpc.Error("Bad account number"), err)
+	assert.False(t, errors.Is(err, rpc.ErrAccountNotFound))
+}
+
+func TestClientNoURL(t *testing.T) {
+	_, err := (&rpc.Client{}).AccountInfo(testAccount)
+	assert.EqualError(t, err, "rpc url not set")
+}
+
+func TestClientHTTPStatus(t *testing.T) {
+	for status, body := range map[int]string{
+		http.StatusTooManyRequests:     "<html>slow down</html>",
+		http.StatusInternalServerError: "{}",
+	} {
+		status, body := status, body
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		}))
+		defer s.Close()
+		_, err := (&rpc.Client{URL: s.URL}).AccountWeight(testAccount)
+		assert.EqualError(t, err, fmt.Sprintf("%d %s", status, http.StatusText(status)))
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"error":"Internal server error in RPC"}`)
+	}))
+	defer s.Close()
+	weight, err := (&rpc.Client{URL: s.URL}).AccountWeight(testAccount)
+	assert.Equal(t, rpc.Error("Internal server error in RPC"), err)
+	assert.Nil(t, weight)
+}
+
+func TestClientBareNumbers(t *testing.T) {
+	c := newTestClient(t, `{"count":1000,"unchecked":10,"cemented":990}`)
+	count, err := c.BlockCountFull()
+	require.Nil(t, err)
+	assert.Equal(t, uint64(1000), count.Count)
+	assert.Equal(t, uint64(990), count.Cemented)
+
+	c = newTestClient(t, `{"frontier":"`+testBlockInfoHash+`","balance":134000000000000000000000000,"block_count":3,`+
+		`"modified_timestamp":1501793775,"confirmed_height":"7"}`)
+	info, err := c.AccountInfo(testAccount)
+	require.Nil(t, err)
+	assert.Equal(t, testBlockInfoHash, info.Frontier.String())
+	assert.Equal(t, rpc.Uint64(1501793775), info.ModifiedTimestamp)
+	assert.Equal(t, rpc.Uint64(7), info.ConfirmedHeight)
+	assert.Equal(t, rpc.Uint64(3), info.BlockCount)
+	assert.Equal(t, "134000000000000000000000000", info.Balance.String())
+}
@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter limits the rate of requests made by a Client. It is satisfied by
+// *rate.Limiter from golang.org/x/time/rate.
+type Limiter interface {
+	// Wait blocks until a request may be made or ctx is done.
+	Wait(ctx context.Context) error
+}
+
+type intervalLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter returns a Limiter that allows up to perSecond requests every
+// second, evenly spaced. The same Limiter may be shared between clients.
+func NewLimiter(perSecond float64) Limiter {
+	return &intervalLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *intervalLimiter) Wait(ctx context.Context) error {
+	l.mutex.Lock()
+	now := time.Now()
+	t := l.next
+	if t.Before(now) {
+		t = now
+	}
+	l.next = t.Add(l.interval)
+	l.mutex.Unlock()
+	timer := time.NewTimer(t.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
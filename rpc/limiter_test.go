@@ -0,0 +1,30 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	c := newTestClient(t, `{"count":"1","unchecked":"0","cemented":"1"}`)
+	c.Limiter = rpc.NewLimiter(20)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, _, _, err := c.BlockCount()
+		require.Nil(t, err)
+	}
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Ctx = ctx
+	c.Limiter = rpc.NewLimiter(0.001)
+	c.Limiter.Wait(context.Background())
+	_, _, _, err := c.BlockCount()
+	assert.Equal(t, context.Canceled, err)
+}
@@ -1,6 +1,10 @@
 package rpc
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 // AvailableSupply returns how many raw are in the public supply.
 func (c *Client) AvailableSupply() (available *RawAmount, err error) {
@@ -12,3 +16,128 @@ func (c *Client) AvailableSupply() (available *RawAmount, err error) {
 	err = json.Unmarshal(resp, &v)
 	return v.Available, err
 }
+
+// ActiveDifficulty reports the difficulty values of the network.
+type ActiveDifficulty struct {
+	NetworkMinimum        HexData `json:"network_minimum"`
+	NetworkReceiveMinimum HexData `json:"network_receive_minimum"`
+	NetworkCurrent        HexData `json:"network_current"`
+	NetworkReceiveCurrent HexData `json:"network_receive_current"`
+	Multiplier            float64 `json:"multiplier,string"`
+}
+
+// ActiveDifficulty returns the difficulty values for the minimum required on
+// the network as well as the current active difficulty seen on the network.
+func (c *Client) ActiveDifficulty() (difficulty ActiveDifficulty, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "active_difficulty"})
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(resp, &difficulty)
+	return
+}
+
+// ConfirmationEntry reports a recently confirmed election.
+type ConfirmationEntry struct {
+	Hash         BlockHash
+	Duration     time.Duration
+	Time         time.Time
+	Tally        *RawAmount
+	RequestCount uint64
+}
+
+// ConfirmationHistory returns the average time taken and the details of
+// recent elections that were confirmed.
+func (c *Client) ConfirmationHistory() (avgTime time.Duration, confirmations []ConfirmationEntry, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "confirmation_history"})
+	if err != nil {
+		return
+	}
+	var v struct {
+		ConfirmationStats struct {
+			Average Uint64
+		} `json:"confirmation_stats"`
+		// confirmations may come as an empty string instead of an array
+		Confirmations json.RawMessage
+	}
+	if err = json.Unmarshal(resp, &v); err != nil {
+		return
+	}
+	avgTime = time.Duration(v.ConfirmationStats.Average) * time.Millisecond
+	var entries []struct {
+		Hash         BlockHash
+		Duration     Uint64
+		Time         Uint64
+		Tally        *RawAmount
+		RequestCount Uint64 `json:"request_count"`
+	}
+	_ = json.Unmarshal(v.Confirmations, &entries)
+	for _, e := range entries {
+		confirmations = append(confirmations, ConfirmationEntry{
+			Hash:         e.Hash,
+			Duration:     time.Duration(e.Duration) * time.Millisecond,
+			Time:         time.Unix(int64(e.Time), 0),
+			Tally:        e.Tally,
+			RequestCount: uint64(e.RequestCount),
+		})
+	}
+	return
+}
+
+// ConfirmationQuorum reports the voting weight needed to confirm blocks and
+// the weight currently online.
+type ConfirmationQuorum struct {
+	// QuorumDelta is the weight of votes needed to confirm a block.
+	QuorumDelta               *RawAmount `json:"quorum_delta"`
+	OnlineWeightQuorumPercent Uint64     `json:"online_weight_quorum_percent"`
+	OnlineWeightMinimum       *RawAmount `json:"online_weight_minimum"`
+	OnlineStakeTotal          *RawAmount `json:"online_stake_total"`
+	TrendedStakeTotal         *RawAmount `json:"trended_stake_total"`
+	// PeersStakeTotal is the weight of the representatives the node is
+	// connected to.
+	PeersStakeTotal *RawAmount `json:"peers_stake_total"`
+}
+
+// ConfirmationQuorum returns the quorum values of the network.
+func (c *Client) ConfirmationQuorum() (quorum ConfirmationQuorum, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "confirmation_quorum"})
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(resp, &quorum)
+	return
+}
+
+// DefaultConfirmationDelay is the delay estimated by
+// EstimateConfirmationDelay when the node has no recent confirmations.
+const DefaultConfirmationDelay = 5 * time.Second
+
+// EstimateConfirmationDelay roughly estimates how long a block published now
+// will take to be confirmed. This is the average duration of recent
+// elections, scaled by the active difficulty multiplier, which exceeds 1
+// when the network is saturated. An error is returned if the node's peers
+// don't have the weight to reach quorum, as nothing can be confirmed then.
+func (c *Client) EstimateConfirmationDelay() (delay time.Duration, err error) {
+	quorum, err := c.ConfirmationQuorum()
+	if err != nil {
+		return
+	}
+	if quorum.PeersStakeTotal != nil && quorum.QuorumDelta != nil &&
+		quorum.PeersStakeTotal.Cmp(&quorum.QuorumDelta.Int) < 0 {
+		return 0, errors.New("peers are below quorum")
+	}
+	difficulty, err := c.ActiveDifficulty()
+	if err != nil {
+		return
+	}
+	if delay, _, err = c.ConfirmationHistory(); err != nil {
+		return
+	}
+	if delay == 0 {
+		delay = DefaultConfirmationDelay
+	}
+	if difficulty.Multiplier > 1 {
+		delay = time.Duration(float64(delay) * difficulty.Multiplier)
+	}
+	return
+}
@@ -3,7 +3,9 @@ package rpc_test
 import (
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/hectorchu/gonano/rpc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -14,3 +16,54 @@ func TestAvailableSupply(t *testing.T) {
 	expectedSupply, _ := new(big.Int).SetString("133000000000000000000000000000000000000", 10)
 	assert.True(t, available.Cmp(expectedSupply) > 0)
 }
+
+func TestConfirmationHistory(t *testing.T) {
+	c := newTestClient(t, `{"confirmation_stats":{"count":"1","average":"5000"},"confirmations":[{"hash":"`+
+		testBlockInfoHash+`","duration":"4000","time":"1544819986","tally":"80394786589602980996311817874549318248",`+
+		`"blocks":"1","voters":"37","request_count":"2"}]}`)
+	avg, confirmations, err := c.ConfirmationHistory()
+	require.Nil(t, err)
+	assert.Equal(t, 5*time.Second, avg)
+	require.Len(t, confirmations, 1)
+	assert.Equal(t, testBlockInfoHash, confirmations[0].Hash.String())
+	assert.Equal(t, 4*time.Second, confirmations[0].Duration)
+	assert.Equal(t, int64(1544819986), confirmations[0].Time.Unix())
+	assert.Equal(t, "80394786589602980996311817874549318248", confirmations[0].Tally.String())
+	assert.Equal(t, uint64(2), confirmations[0].RequestCount)
+
+	_, confirmations, err = newTestClient(t, `{"confirmation_stats":{"count":"0"},"confirmations":""}`).ConfirmationHistory()
+	require.Nil(t, err)
+	assert.Empty(t, confirmations)
+}
+
+func TestConfirmationQuorum(t *testing.T) {
+	c := newTestClient(t, `{"quorum_delta":"41469707173777717318245825935516662250","online_weight_quorum_percent":"50",`+
+		`"online_weight_minimum":"60000000000000000000000000000000000000","online_stake_total":"82939414347555434636491651871033324568",`+
+		`"trended_stake_total":"81939414347555434636491651871033324568","peers_stake_total":"69026910610720098597176027400951402360"}`)
+	quorum, err := c.ConfirmationQuorum()
+	require.Nil(t, err)
+	assertEqualBig(t, "41469707173777717318245825935516662250", &quorum.QuorumDelta.Int)
+	assert.Equal(t, rpc.Uint64(50), quorum.OnlineWeightQuorumPercent)
+	assertEqualBig(t, "69026910610720098597176027400951402360", &quorum.PeersStakeTotal.Int)
+}
+
+func TestEstimateConfirmationDelay(t *testing.T) {
+	responses := map[string]string{
+		"confirmation_quorum":  `{"quorum_delta":"50","peers_stake_total":"80"}`,
+		"active_difficulty":    `{"multiplier":"1.5"}`,
+		"confirmation_history": `{"confirmation_stats":{"count":"1","average":"2000"},"confirmations":[]}`,
+	}
+	delay, err := newActionClient(t, responses).EstimateConfirmationDelay()
+	require.Nil(t, err)
+	assert.Equal(t, 3*time.Second, delay)
+
+	responses["confirmation_history"] = `{"confirmation_stats":{"count":"0"},"confirmations":""}`
+	responses["active_difficulty"] = `{"multiplier":"0.5"}`
+	delay, err = newActionClient(t, responses).EstimateConfirmationDelay()
+	require.Nil(t, err)
+	assert.Equal(t, rpc.DefaultConfirmationDelay, delay)
+
+	responses["confirmation_quorum"] = `{"quorum_delta":"50","peers_stake_total":"40"}`
+	_, err = newActionClient(t, responses).EstimateConfirmationDelay()
+	assert.NotNil(t, err)
+}
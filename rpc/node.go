@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Bootstrap initializes bootstrap to specific IP address and port.
+func (c *Client) Bootstrap(address string, port uint16) (err error) {
+	_, err = c.send(map[string]interface{}{
+		"action":  "bootstrap",
+		"address": address,
+		"port":    strconv.Itoa(int(port)),
+	})
+	return
+}
+
+// BootstrapAny initializes multi-connection bootstrap to random peers.
+func (c *Client) BootstrapAny() (err error) {
+	_, err = c.send(map[string]interface{}{"action": "bootstrap_any"})
+	return
+}
+
+// BootstrapLazy initializes lazy bootstrap with given block hash.
+// If force is true, all current bootstrap attempts are stopped first.
+func (c *Client) BootstrapLazy(hash BlockHash, force bool) (err error) {
+	_, err = c.send(map[string]interface{}{
+		"action": "bootstrap_lazy",
+		"hash":   hash,
+		"force":  force,
+	})
+	return
+}
+
+// BootstrapConnections reports the connections used by bootstrap attempts.
+type BootstrapConnections struct {
+	Clients           Uint64 `json:"clients"`
+	Connections       Uint64 `json:"connections"`
+	Idle              Uint64 `json:"idle"`
+	TargetConnections Uint64 `json:"target_connections"`
+	Pulls             Uint64 `json:"pulls"`
+}
+
+// BootstrapAttempt reports the progress of a single bootstrap attempt.
+type BootstrapAttempt struct {
+	ID            string `json:"id"`
+	Required      bool   `json:"required,string"`
+	Mode          string `json:"mode"`
+	Started       bool   `json:"started,string"`
+	Pulling       Uint64 `json:"pulling"`
+	TotalBlocks   Uint64 `json:"total_blocks"`
+	RequeuedPulls Uint64 `json:"requeued_pulls"`
+	Duration      Uint64 `json:"duration"`
+}
+
+// BootstrapStatus reports the status of the node's bootstrap attempts.
+type BootstrapStatus struct {
+	BootstrapThreads     Uint64               `json:"bootstrap_threads"`
+	RunningAttemptsCount Uint64               `json:"running_attempts_count"`
+	TotalAttemptsCount   Uint64               `json:"total_attempts_count"`
+	Connections          BootstrapConnections `json:"connections"`
+	Attempts             []BootstrapAttempt   `json:"attempts"`
+}
+
+// BootstrapStatus returns information about current bootstrap attempts.
+func (c *Client) BootstrapStatus() (status BootstrapStatus, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "bootstrap_status"})
+	if err != nil {
+		return
+	}
+	var v struct {
+		BootstrapStatus
+		// attempts may come as an empty string instead of an array
+		Attempts json.RawMessage `json:"attempts"`
+	}
+	if err = json.Unmarshal(resp, &v); err != nil {
+		return
+	}
+	status = v.BootstrapStatus
+	_ = json.Unmarshal(v.Attempts, &status.Attempts)
+	return
+}
+
+// Keepalive tells the node to send a keepalive packet to address:port.
+func (c *Client) Keepalive(address string, port uint16) (err error) {
+	_, err = c.send(map[string]interface{}{
+		"action":  "keepalive",
+		"address": address,
+		"port":    strconv.Itoa(int(port)),
+	})
+	return
+}
+
+// Peer reports details of a peer connected to the node.
+type Peer struct {
+	ProtocolVersion Uint64 `json:"protocol_version"`
+	NodeID          string `json:"node_id"`
+	Type            string `json:"type"`
+}
+
+// Peers returns a list of pairs of online peer IPv6:port and its details.
+// Only the protocol version is set for nodes that don't report the details.
+func (c *Client) Peers() (peers map[string]Peer, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "peers", "peer_details": true})
+	if err != nil {
+		return
+	}
+	var u struct{ Peers string }
+	if err = json.Unmarshal(resp, &u); err == nil && u.Peers == "" {
+		return
+	}
+	var v struct{ Peers map[string]json.RawMessage }
+	if err = json.Unmarshal(resp, &v); err != nil {
+		return
+	}
+	peers = make(map[string]Peer, len(v.Peers))
+	for address, data := range v.Peers {
+		var peer Peer
+		// Nodes that ignore peer_details give the protocol version alone.
+		if len(data) > 0 && data[0] != '{' {
+			err = json.Unmarshal(data, &peer.ProtocolVersion)
+		} else {
+			err = json.Unmarshal(data, &peer)
+		}
+		if err != nil {
+			return nil, err
+		}
+		peers[address] = peer
+	}
+	return
+}
+
+// NodeID returns the ID of the node, as it identifies itself to peers.
+func (c *Client) NodeID() (id string, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "node_id"})
+	if err != nil {
+		return
+	}
+	var v struct {
+		NodeID string `json:"node_id"`
+	}
+	err = json.Unmarshal(resp, &v)
+	return v.NodeID, err
+}
+
+// StatsClear clears the node's statistics counters.
+func (c *Client) StatsClear() (err error) {
+	_, err = c.send(map[string]interface{}{"action": "stats_clear"})
+	return
+}
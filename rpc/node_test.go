@@ -0,0 +1,105 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootstrap(t *testing.T) {
+	c, body := newRecordingClient(t, `{"success":""}`)
+	require.Nil(t, c.Bootstrap("::ffff:138.201.94.249", 7075))
+	assert.Equal(t, "bootstrap", body()["action"])
+	assert.Equal(t, "::ffff:138.201.94.249", body()["address"])
+	assert.Equal(t, "7075", body()["port"])
+
+	require.Nil(t, c.BootstrapAny())
+	assert.Equal(t, "bootstrap_any", body()["action"])
+
+	c, body = newRecordingClient(t, `{"started":"1","key_inserted":"0"}`)
+	require.Nil(t, c.BootstrapLazy(hexString(testBlockInfoHash), true))
+	assert.Equal(t, "bootstrap_lazy", body()["action"])
+	assert.Equal(t, testBlockInfoHash, body()["hash"])
+	assert.Equal(t, true, body()["force"])
+
+	assert.NotNil(t, newTestClient(t, `{"error":"Bootstrapping is disabled"}`).BootstrapAny())
+}
+
+func TestBootstrapStatus(t *testing.T) {
+	c := newTestClient(t, `{"bootstrap_threads":"2","running_attempts_count":"1","total_attempts_count":"5",`+
+		`"connections":{"clients":"5","connections":"4","idle":"0","target_connections":"64","pulls":"1158"},`+
+		`"attempts":[{"id":"EE1B3A6C6A9C89CC","required":"true","mode":"legacy","started":"true","pulling":"3",`+
+		`"total_blocks":"12345","requeued_pulls":"2","duration":"14"}]}`)
+	status, err := c.BootstrapStatus()
+	require.Nil(t, err)
+	assert.Equal(t, rpc.Uint64(2), status.BootstrapThreads)
+	assert.Equal(t, rpc.Uint64(1), status.RunningAttemptsCount)
+	assert.Equal(t, rpc.Uint64(5), status.TotalAttemptsCount)
+	assert.Equal(t, rpc.Uint64(64), status.Connections.TargetConnections)
+	assert.Equal(t, rpc.Uint64(1158), status.Connections.Pulls)
+	require.Len(t, status.Attempts, 1)
+	assert.Equal(t, "EE1B3A6C6A9C89CC", status.Attempts[0].ID)
+	assert.True(t, status.Attempts[0].Required)
+	assert.Equal(t, "legacy", status.Attempts[0].Mode)
+	assert.Equal(t, rpc.Uint64(12345), status.Attempts[0].TotalBlocks)
+
+	for _, attempts := range []string{`""`, `0`} {
+		c = newTestClient(t, `{"bootstrap_threads":"2","running_attempts_count":"0","total_attempts_count":"0",`+
+			`"connections":{"clients":"0","connections":"0","idle":"0","target_connections":"0","pulls":"0"},`+
+			`"attempts":`+attempts+`}`)
+		status, err = c.BootstrapStatus()
+		require.Nil(t, err, attempts)
+		assert.Empty(t, status.Attempts)
+		assert.Equal(t, rpc.Uint64(2), status.BootstrapThreads)
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	c, body := newRecordingClient(t, `{"started":"1"}`)
+	require.Nil(t, c.Keepalive("::ffff:192.169.0.1", 1024))
+	assert.Equal(t, "keepalive", body()["action"])
+	assert.Equal(t, "::ffff:192.169.0.1", body()["address"])
+	assert.Equal(t, "1024", body()["port"])
+}
+
+func TestPeers(t *testing.T) {
+	const nodeID = "node_1cmi8difuruopgzsnb4ybrnnj5rproxwuwe5mad7ucbsekakiwn37qqg1zo5"
+	c, body := newRecordingClient(t, `{"peers":{"[::ffff:172.17.0.1]:32841":`+
+		`{"protocol_version":"18","node_id":"`+nodeID+`","type":"tcp"}}}`)
+	peers, err := c.Peers()
+	require.Nil(t, err)
+	assert.Equal(t, true, body()["peer_details"])
+	assert.Equal(t, map[string]rpc.Peer{
+		"[::ffff:172.17.0.1]:32841": {ProtocolVersion: 18, NodeID: nodeID, Type: "tcp"},
+	}, peers)
+
+	peers, err = newTestClient(t, `{"peers":{"[::ffff:172.17.0.1]:32841":"16","[::ffff:172.17.0.2]:7075":17}}`).Peers()
+	require.Nil(t, err)
+	assert.Equal(t, map[string]rpc.Peer{
+		"[::ffff:172.17.0.1]:32841": {ProtocolVersion: 16},
+		"[::ffff:172.17.0.2]:7075":  {ProtocolVersion: 17},
+	}, peers)
+
+	peers, err = newTestClient(t, `{"peers":""}`).Peers()
+	require.Nil(t, err)
+	assert.Empty(t, peers)
+}
+
+func TestNodeID(t *testing.T) {
+	const nodeID = "node_1cmi8difuruopgzsnb4ybrnnj5rproxwuwe5mad7ucbsekakiwn37qqg1zo5"
+	c, body := newRecordingClient(t, `{"public":"2A31D5A6FE7A2B0E7FCC5F13145F70C3A2EB6A4F8ED4F6E9DC2D3C35173F2024",`+
+		`"as_account":"nano_1cmi8difuruopgzsnb4ybrnnj5rproxwuwe5mad7ucbsekakiwn37qqg1zo5","node_id":"`+nodeID+`"}`)
+	id, err := c.NodeID()
+	require.Nil(t, err)
+	assert.Equal(t, "node_id", body()["action"])
+	assert.Equal(t, nodeID, id)
+}
+
+func TestStatsClear(t *testing.T) {
+	c, body := newRecordingClient(t, `{"success":""}`)
+	require.Nil(t, c.StatsClear())
+	assert.Equal(t, "stats_clear", body()["action"])
+	assert.NotNil(t, newTestClient(t, `{"error":"Unknown command"}`).StatsClear())
+}
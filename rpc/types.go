@@ -1,10 +1,14 @@
 package rpc
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/hectorchu/gonano/util"
@@ -16,8 +20,8 @@ type AccountHistory struct {
 	Type           string     `json:"type"`
 	Account        string     `json:"account"`
 	Amount         *RawAmount `json:"amount"`
-	LocalTimestamp uint64     `json:"local_timestamp,string"`
-	Height         uint64     `json:"height,string"`
+	LocalTimestamp Uint64     `json:"local_timestamp"`
+	Height         Uint64     `json:"height"`
 	Hash           BlockHash  `json:"hash"`
 }
 
@@ -32,8 +36,8 @@ type AccountHistoryRaw struct {
 	Subtype        string     `json:"subtype"`
 	Account        string     `json:"account"`
 	Amount         *RawAmount `json:"amount"`
-	LocalTimestamp uint64     `json:"local_timestamp,string"`
-	Height         uint64     `json:"height,string"`
+	LocalTimestamp Uint64     `json:"local_timestamp"`
+	Height         Uint64     `json:"height"`
 	Hash           BlockHash  `json:"hash"`
 	Work           HexData    `json:"work"`
 	Signature      HexData    `json:"signature"`
@@ -47,17 +51,29 @@ type AccountInfo struct {
 	OpenBlock                  BlockHash  `json:"open_block"`
 	RepresentativeBlock        BlockHash  `json:"representative_block"`
 	Balance                    *RawAmount `json:"balance"`
-	ModifiedTimestamp          uint64     `json:"modified_timestamp,string"`
-	BlockCount                 uint64     `json:"block_count,string"`
-	ConfirmationHeight         uint64     `json:"confirmation_height,string"`
+	ModifiedTimestamp          Uint64     `json:"modified_timestamp"`
+	BlockCount                 Uint64     `json:"block_count"`
+	ConfirmationHeight         Uint64     `json:"confirmation_height"`
 	ConfirmationHeightFrontier BlockHash  `json:"confirmation_height_frontier"`
-	AccountVersion             uint64     `json:"account_version,string"`
+	AccountVersion             Uint64     `json:"account_version"`
 	Representative             string     `json:"representative"`
 	Weight                     *RawAmount `json:"weight"`
 	Pending                    *RawAmount `json:"pending"`
+	ConfirmedBalance           *RawAmount `json:"confirmed_balance"`
+	ConfirmedHeight            Uint64     `json:"confirmed_height"`
+	ConfirmedFrontier          BlockHash  `json:"confirmed_frontier"`
+	ConfirmedRepresentative    string     `json:"confirmed_representative"`
+	ConfirmedPending           *RawAmount `json:"confirmed_pending"`
 }
 
 // Block corresponds to the JSON representation of a block.
+// Legacy send, receive, open and change blocks are also supported, in which
+// case only the fields relevant to the block type are set.
+//
+// Work holds the 64-bit work value in big-endian order, as returned by
+// pow.Generate and WorkGenerate, and is marshalled as the hex string the node
+// expects. Note that the work is hashed in the reverse, little-endian, order
+// when validating it.
 type Block struct {
 	Type           string     `json:"type"`
 	Account        string     `json:"account"`
@@ -66,41 +82,180 @@ type Block struct {
 	Balance        *RawAmount `json:"balance"`
 	Link           BlockHash  `json:"link"`
 	LinkAsAccount  string     `json:"link_as_account"`
+	Destination    string     `json:"destination,omitempty"`
+	Source         BlockHash  `json:"source,omitempty"`
 	Signature      HexData    `json:"signature"`
 	Work           HexData    `json:"work"`
 }
 
+// UnmarshalJSON sets *b to a copy of data.
+func (b *Block) UnmarshalJSON(data []byte) (err error) {
+	type block Block
+	var v struct {
+		*block
+		// legacy send blocks encode the balance in hex
+		Balance json.RawMessage `json:"balance"`
+	}
+	v.block = (*block)(b)
+	if err = json.Unmarshal(data, &v); err != nil {
+		return
+	}
+	b.Balance = nil
+	if len(v.Balance) == 0 || string(v.Balance) == "null" {
+		return
+	}
+	b.Balance = new(RawAmount)
+	if b.Type != "send" {
+		return b.Balance.UnmarshalJSON(v.Balance)
+	}
+	var s string
+	if err = json.Unmarshal(v.Balance, &s); err != nil {
+		return
+	}
+	if _, ok := b.Balance.SetString(s, 16); !ok {
+		err = errors.New("unable to parse amount")
+	}
+	return
+}
+
+var (
+	epochV1Link = append([]byte("epoch v1 block"), make([]byte, 18)...)
+	epochV2Link = append([]byte("epoch v2 block"), make([]byte, 18)...)
+)
+
+// Subtype returns the subtype of the block as far as it can be determined
+// from the block alone. For legacy blocks this is the block type. For state
+// blocks only epoch blocks are recognized, by their link, and the empty
+// string is returned otherwise since telling sends from receives and changes
+// requires the balance of the previous block (see BlockInfo.Subtype).
+func (b *Block) Subtype() string {
+	switch b.Type {
+	case "send", "receive", "open", "change":
+		return b.Type
+	case "state":
+		if bytes.Equal(b.Link, epochV1Link) || bytes.Equal(b.Link, epochV2Link) {
+			return "epoch"
+		}
+	}
+	return ""
+}
+
+// WorkHex returns the work of the block as a lowercase hex string, as it
+// appears in the block's JSON.
+func (b *Block) WorkHex() string {
+	return hex.EncodeToString(b.Work)
+}
+
 // Hash calculates the block hash.
 func (b *Block) Hash() (hash BlockHash, err error) {
 	h, err := blake2b.New256(nil)
 	if err != nil {
 		return
 	}
-	h.Write(make([]byte, 31))
-	h.Write([]byte{6})
-	pubkey, err := util.AddressToPubkey(b.Account)
-	if err != nil {
+	writeAddress := func(address string) (err error) {
+		pubkey, err := util.AddressToPubkey(address)
+		if err == nil {
+			h.Write(pubkey)
+		}
 		return
 	}
-	h.Write(pubkey)
-	h.Write(b.Previous)
-	pubkey, err = util.AddressToPubkey(b.Representative)
-	if err != nil {
-		return
+	switch b.Type {
+	case "send":
+		h.Write(b.Previous)
+		if err = writeAddress(b.Destination); err != nil {
+			return
+		}
+		h.Write(b.Balance.FillBytes(make([]byte, 16)))
+	case "receive":
+		h.Write(b.Previous)
+		h.Write(b.Source)
+	case "open":
+		h.Write(b.Source)
+		if err = writeAddress(b.Representative); err != nil {
+			return
+		}
+		if err = writeAddress(b.Account); err != nil {
+			return
+		}
+	case "change":
+		h.Write(b.Previous)
+		if err = writeAddress(b.Representative); err != nil {
+			return
+		}
+	default:
+		h.Write(make([]byte, 31))
+		h.Write([]byte{6})
+		if err = writeAddress(b.Account); err != nil {
+			return
+		}
+		h.Write(b.Previous)
+		if err = writeAddress(b.Representative); err != nil {
+			return
+		}
+		h.Write(b.Balance.FillBytes(make([]byte, 16)))
+		h.Write(b.Link)
 	}
-	h.Write(pubkey)
-	h.Write(b.Balance.FillBytes(make([]byte, 16)))
-	h.Write(b.Link)
 	return h.Sum(nil), nil
 }
 
+// BlockCount reports the block counts of the ledger.
+type BlockCount struct {
+	Count, Unchecked, Cemented uint64
+	// Counters holds every numeric count reported by the node, keyed by
+	// name, including those added by node versions newer than this package.
+	Counters map[string]uint64
+}
+
+// UnmarshalJSON sets *b to the counts in data. Fields that aren't counts
+// are ignored.
+func (b *BlockCount) UnmarshalJSON(data []byte) (err error) {
+	var v map[string]json.RawMessage
+	if err = json.Unmarshal(data, &v); err != nil {
+		return
+	}
+	b.Counters = make(map[string]uint64)
+	for name, value := range v {
+		var n Uint64
+		if json.Unmarshal(value, &n) == nil {
+			b.Counters[name] = uint64(n)
+		}
+	}
+	b.Count = b.Counters["count"]
+	b.Unchecked = b.Counters["unchecked"]
+	b.Cemented = b.Counters["cemented"]
+	return
+}
+
 // BlockHash represents a block hash.
 type BlockHash []byte
 
+// ParseBlockHash decodes a hex encoded block hash.
+func ParseBlockHash(s string) (h BlockHash, err error) {
+	if h, err = hex.DecodeString(s); err != nil {
+		return
+	}
+	if len(h) != 32 {
+		return nil, errors.New("invalid block hash length")
+	}
+	return
+}
+
+// String returns the uppercase hex encoding of h, as used by the node.
 func (h BlockHash) String() string {
 	return strings.ToUpper(hex.EncodeToString(h))
 }
 
+// MarshalText returns the hex encoding of h.
+func (h BlockHash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText sets *h to the decoding of the hex string text.
+func (h *BlockHash) UnmarshalText(text []byte) (err error) {
+	*h, err = hex.DecodeString(string(text))
+	return
+}
+
 // MarshalJSON returns the JSON encoding of h.
 func (h BlockHash) MarshalJSON() ([]byte, error) {
 	return json.Marshal(h.String())
@@ -112,20 +267,90 @@ func (h *BlockHash) UnmarshalJSON(data []byte) (err error) {
 	if err = json.Unmarshal(data, &s); err != nil {
 		return
 	}
-	*h, err = hex.DecodeString(s)
-	return
+	return h.UnmarshalText([]byte(s))
 }
 
 // BlockInfo retrieves a json representation of a block.
+//
+// Amount is the amount moved by the block: sent for a send, received for a
+// receive or open, and zero for a change or epoch. Balance is the balance of
+// the account after the block. Subtype is one of send, receive, open, change
+// or epoch; for legacy blocks, which the node reports without a subtype, it
+// is the block type. Amount and Balance are zero rather than nil when the
+// node omits them.
 type BlockInfo struct {
 	BlockAccount   string     `json:"block_account"`
 	Amount         *RawAmount `json:"amount"`
 	Balance        *RawAmount `json:"balance"`
-	Height         uint64     `json:"height,string"`
-	LocalTimestamp uint64     `json:"local_timestamp,string"`
+	Height         Uint64     `json:"height"`
+	LocalTimestamp Uint64     `json:"local_timestamp"`
 	Confirmed      bool       `json:"confirmed,string"`
 	Contents       *Block     `json:"contents"`
 	Subtype        string     `json:"subtype"`
+	// Pending reports whether a send is still receivable. It is only set
+	// if requested with BlocksInfoOptions.Pending.
+	Pending bool `json:"-"`
+	// SourceAccount is the account that sent the amount pocketed by a
+	// receive. It is only set if requested with BlocksInfoOptions.Source.
+	SourceAccount string `json:"source_account"`
+}
+
+// UnmarshalJSON sets *b to a copy of data.
+func (b *BlockInfo) UnmarshalJSON(data []byte) (err error) {
+	type blockInfo BlockInfo
+	var v struct {
+		*blockInfo
+		// pending is "0" or "1" rather than a boolean.
+		Pending string `json:"pending"`
+	}
+	v.blockInfo = (*blockInfo)(b)
+	if err = json.Unmarshal(data, &v); err != nil {
+		return
+	}
+	b.Pending = v.Pending == "1"
+	if b.SourceAccount == "0" {
+		// Blocks other than receives have a source account of "0".
+		b.SourceAccount = ""
+	}
+	if b.Amount == nil {
+		b.Amount = new(RawAmount)
+	}
+	if b.Balance == nil {
+		b.Balance = new(RawAmount)
+	}
+	if b.Subtype == "" && b.Contents != nil {
+		b.Subtype = b.Contents.Subtype()
+	}
+	return
+}
+
+// IsSend reports whether the block is a send, which is the only kind of
+// block that can be received.
+func (b *BlockInfo) IsSend() bool {
+	return b.Subtype == "send"
+}
+
+// BlockSubtype is the subtype of a block, which tells the kind of a state
+// block that can't be told from its type.
+type BlockSubtype string
+
+// Block subtypes.
+const (
+	SubtypeSend    BlockSubtype = "send"
+	SubtypeReceive BlockSubtype = "receive"
+	SubtypeOpen    BlockSubtype = "open"
+	SubtypeChange  BlockSubtype = "change"
+	SubtypeEpoch   BlockSubtype = "epoch"
+)
+
+// ParseBlockSubtype converts s to a BlockSubtype, failing if it isn't one of
+// the known subtypes.
+func ParseBlockSubtype(s string) (subtype BlockSubtype, err error) {
+	switch subtype = BlockSubtype(s); subtype {
+	case SubtypeSend, SubtypeReceive, SubtypeOpen, SubtypeChange, SubtypeEpoch:
+		return
+	}
+	return "", fmt.Errorf("unknown block subtype %q", s)
 }
 
 // HexData represents generic hex data.
@@ -146,18 +371,135 @@ func (h *HexData) UnmarshalJSON(data []byte) (err error) {
 	return
 }
 
+// ParseHexData decodes the hex string s.
+func ParseHexData(s string) (h HexData, err error) {
+	return hex.DecodeString(s)
+}
+
+// HexDataFromUint64 returns v in big-endian byte order, which is how work
+// difficulties are represented.
+func HexDataFromUint64(v uint64) HexData {
+	h := make(HexData, 8)
+	binary.BigEndian.PutUint64(h, v)
+	return h
+}
+
+// Uint64 returns h as a big-endian integer, so that difficulties can be
+// compared. Only the last 8 bytes of h are used.
+func (h HexData) Uint64() (v uint64) {
+	if len(h) > 8 {
+		h = h[len(h)-8:]
+	}
+	for _, b := range h {
+		v = v<<8 | uint64(b)
+	}
+	return
+}
+
+// String returns h in hex.
+func (h HexData) String() string {
+	return hex.EncodeToString(h)
+}
+
+// Uint64 is a number that the node reports as a JSON string, but that some
+// node versions and proxies send as a bare JSON number. Both are accepted.
+type Uint64 uint64
+
+// MarshalJSON returns the JSON encoding of u, as a string like the node's.
+func (u Uint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(u), 10))
+}
+
+// UnmarshalJSON sets *u from a JSON string or number.
+func (u *Uint64) UnmarshalJSON(data []byte) (err error) {
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err = json.Unmarshal(data, &s); err != nil {
+			return
+		}
+	} else if s == "null" {
+		s = ""
+	}
+	if s == "" {
+		*u = 0
+		return
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return errors.New("unable to parse number")
+	}
+	*u = Uint64(v)
+	return
+}
+
 // RawAmount represents an amount of nano in RAWs.
 type RawAmount struct{ big.Int }
 
+// A nil *RawAmount is treated as zero by the methods below.
+func (r *RawAmount) bigInt() *big.Int {
+	if r == nil {
+		return new(big.Int)
+	}
+	return &r.Int
+}
+
+// Plus returns a new RawAmount set to r + x.
+func (r *RawAmount) Plus(x *big.Int) *RawAmount {
+	z := new(RawAmount)
+	z.Int.Add(r.bigInt(), x)
+	return z
+}
+
+// Minus returns a new RawAmount set to r - x.
+func (r *RawAmount) Minus(x *big.Int) *RawAmount {
+	z := new(RawAmount)
+	z.Int.Sub(r.bigInt(), x)
+	return z
+}
+
+// Cmp compares r and x and returns -1, 0 or +1.
+func (r *RawAmount) Cmp(x *big.Int) int {
+	return r.bigInt().Cmp(x)
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of r.
+func (r *RawAmount) Sign() int {
+	return r.bigInt().Sign()
+}
+
+// String returns the decimal representation of r in RAWs.
+func (r *RawAmount) String() string {
+	return r.bigInt().String()
+}
+
+// Nano returns r formatted as an amount of NANO.
+func (r *RawAmount) Nano() string {
+	return util.NanoAmount{Raw: r.bigInt()}.String()
+}
+
+// Banano returns r formatted as an amount of BANANO.
+func (r *RawAmount) Banano() string {
+	return util.BananoAmount{Raw: r.bigInt()}.String()
+}
+
 // MarshalJSON returns the JSON encoding of r.
 func (r *RawAmount) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r.String())
 }
 
-// UnmarshalJSON sets *r to a copy of data.
+// UnmarshalJSON sets *r to a copy of data. Both quoted and bare integers
+// are accepted, and an empty string or null is treated as zero.
 func (r *RawAmount) UnmarshalJSON(data []byte) (err error) {
-	var s string
-	if err = json.Unmarshal(data, &s); err != nil {
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err = json.Unmarshal(data, &s); err != nil {
+			return
+		}
+	} else if s == "null" {
+		s = ""
+	}
+	if s == "" {
+		r.SetInt64(0)
 		return
 	}
 	if _, ok := r.SetString(s, 10); !ok {
@@ -0,0 +1,267 @@
+package rpc_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/hectorchu/gonano/pow"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlockHash(t *testing.T) {
+	h, err := rpc.ParseBlockHash("8c1b5d4bbe27f05c7a888d1e691a07c550a81afee16d913ee21e1093888b82fd")
+	require.Nil(t, err)
+	assert.Equal(t, "8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD", h.String())
+	_, err = rpc.ParseBlockHash("8C1B5D4B")
+	assert.NotNil(t, err)
+	_, err = rpc.ParseBlockHash("not hex")
+	assert.NotNil(t, err)
+}
+
+func TestBlockHashText(t *testing.T) {
+	h := rpc.BlockHash(hexString(testBlockInfoHash))
+	text, err := h.MarshalText()
+	require.Nil(t, err)
+	assert.Equal(t, testBlockInfoHash, string(text))
+	var h2 rpc.BlockHash
+	require.Nil(t, h2.UnmarshalText(text))
+	assert.Equal(t, h, h2)
+
+	data, err := json.Marshal(h)
+	require.Nil(t, err)
+	assert.Equal(t, `"`+testBlockInfoHash+`"`, string(data))
+	var h3 rpc.BlockHash
+	require.Nil(t, json.Unmarshal(data, &h3))
+	assert.Equal(t, h, h3)
+}
+
+func TestUint64(t *testing.T) {
+	var v struct{ A, B, C, D rpc.Uint64 }
+	require.Nil(t, json.Unmarshal([]byte(`{"A":"18446744073709551615","B":42,"C":"","D":null}`), &v))
+	assert.Equal(t, rpc.Uint64(18446744073709551615), v.A)
+	assert.Equal(t, rpc.Uint64(42), v.B)
+	assert.Equal(t, rpc.Uint64(0), v.C)
+	assert.Equal(t, rpc.Uint64(0), v.D)
+	assert.NotNil(t, json.Unmarshal([]byte(`{"A":-1}`), &v))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"A":"1.5"}`), &v))
+
+	data, err := json.Marshal(v.B)
+	require.Nil(t, err)
+	assert.Equal(t, `"42"`, string(data))
+}
+
+func TestRawAmount(t *testing.T) {
+	var r rpc.RawAmount
+	r.SetString("1000000000000000000000000000000", 10)
+	sum := r.Plus(big.NewInt(1))
+	assert.Equal(t, "1000000000000000000000000000001", sum.String())
+	assert.Equal(t, "1000000000000000000000000000000", r.String())
+	diff := r.Minus(big.NewInt(1))
+	assert.Equal(t, "999999999999999999999999999999", diff.String())
+	assert.Equal(t, 1, sum.Cmp(&r.Int))
+	assert.Equal(t, -1, diff.Cmp(&r.Int))
+	assert.Equal(t, 1, r.Sign())
+	assert.Equal(t, "1.000000", r.Nano())
+	assert.Equal(t, "10.000000", r.Banano())
+
+	var nilAmount *rpc.RawAmount
+	assert.Equal(t, 0, nilAmount.Sign())
+	assert.Equal(t, "0", nilAmount.String())
+	assert.Equal(t, "5", nilAmount.Plus(big.NewInt(5)).String())
+
+	// The embedded big.Int arithmetic is still reachable.
+	r.Add(&r.Int, big.NewInt(1))
+	assert.Equal(t, "1000000000000000000000000000001", r.String())
+}
+
+func TestRawAmountUnmarshalJSON(t *testing.T) {
+	for data, expected := range map[string]string{
+		`"134000000000000000000000000"`: "134000000000000000000000000",
+		`134000000000000000000000000`:   "134000000000000000000000000",
+		`"0"`:                           "0",
+		`0`:                             "0",
+		`""`:                            "0",
+		`null`:                          "0",
+	} {
+		r := rpc.RawAmount{}
+		r.SetInt64(42)
+		require.Nil(t, r.UnmarshalJSON([]byte(data)), data)
+		assert.Equal(t, expected, r.String(), data)
+	}
+	for _, data := range []string{`"abc"`, `"1.5"`, `1.5`, `true`, `"`} {
+		var r rpc.RawAmount
+		assert.NotNil(t, r.UnmarshalJSON([]byte(data)), data)
+	}
+
+	var v struct {
+		Balance, Pending, Receivable *rpc.RawAmount
+	}
+	require.Nil(t, json.Unmarshal([]byte(`{"balance":"10","pending":"","receivable":null}`), &v))
+	assert.Equal(t, "10", v.Balance.String())
+	assert.Equal(t, 0, v.Pending.Sign())
+	assert.Equal(t, 0, v.Receivable.Sign())
+
+	data, err := json.Marshal(v.Balance)
+	require.Nil(t, err)
+	assert.Equal(t, `"10"`, string(data))
+}
+
+func TestBlockUnmarshalLegacy(t *testing.T) {
+	var b rpc.Block
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"type": "send",
+		"previous": "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948",
+		"destination": "xrb_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx",
+		"balance": "FD89D89D89D89D89D89D89D89D89D89D",
+		"work": "3c82cc724905ee95",
+		"signature": "5B11B17DB9C8FE0CC58CAC6A6EECEF9CB122DA8A81C6D3DB1B5EE3AB065AA8F8CB1D6765C8EB91B58530C5FF5987AD95E6D34BB57F44257E20795EE412E61600"
+	}`), &b))
+	assert.Equal(t, "send", b.Subtype())
+	assert.Equal(t, "xrb_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx", b.Destination)
+	assertEqualBig(t, "337010421085160209006996005437231978653", &b.Balance.Int)
+	assertEqualBytes(t, "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948", b.Previous)
+
+	b = rpc.Block{}
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"type": "receive",
+		"previous": "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948",
+		"source": "E89208DD038FBB269987689621D52292AE9C35941A7484756ECCED92A65093BA",
+		"work": "3c82cc724905ee95",
+		"signature": "5B11B17DB9C8FE0CC58CAC6A6EECEF9CB122DA8A81C6D3DB1B5EE3AB065AA8F8CB1D6765C8EB91B58530C5FF5987AD95E6D34BB57F44257E20795EE412E61600"
+	}`), &b))
+	assert.Equal(t, "receive", b.Subtype())
+	assertEqualBytes(t, "E89208DD038FBB269987689621D52292AE9C35941A7484756ECCED92A65093BA", b.Source)
+	assert.Nil(t, b.Balance)
+
+	b = rpc.Block{}
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"type": "state",
+		"account": "nano_1zcffp784drsmz4oksufxfjut1nb5yh6pg43a6h6bkos39zz19ed6a4r36ny",
+		"previous": "CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E",
+		"representative": "nano_1natrium1o3z5519ifou7xii8crpxpk8y65qmkih8e8bpsjri651oza8imdd",
+		"balance": "134000000000000000000000000",
+		"link": "65706F636820763220626C6F636B000000000000000000000000000000000000"
+	}`), &b))
+	assertEqualBig(t, "134000000000000000000000000", &b.Balance.Int)
+	assert.Equal(t, "epoch", b.Subtype())
+	b.Link = hexString("CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E")
+	assert.Equal(t, "", b.Subtype())
+}
+
+func TestBlockHash(t *testing.T) {
+	// Genesis open block of the live network.
+	b := &rpc.Block{
+		Type:           "open",
+		Source:         hexString("E89208DD038FBB269987689621D52292AE9C35941A7484756ECCED92A65093BA"),
+		Representative: "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+		Account:        "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+	}
+	hash, err := b.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948", hash.String())
+
+	var balance rpc.RawAmount
+	balance.SetString("134000000000000000000000000", 10)
+	b = &rpc.Block{
+		Type:           "state",
+		Account:        testAccount,
+		Previous:       hexString("CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E"),
+		Representative: "nano_1natrium1o3z5519ifou7xii8crpxpk8y65qmkih8e8bpsjri651oza8imdd",
+		Balance:        &balance,
+		Link:           hexString("CEC5287A00F5A50E11A80EC3A63C575D37BFD5BAD87BCB1B7E46DBCBE2F1EC3E"),
+	}
+	hash, err = b.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, testBlockInfoHash, hash.String())
+}
+
+func TestBlockHashLegacy(t *testing.T) {
+	// First send from the genesis account of the live network.
+	balance := new(rpc.RawAmount)
+	balance.SetString("FD89D89D89D89D89D89D89D89D89D89D", 16)
+	b := &rpc.Block{
+		Type:        "send",
+		Previous:    hexString("991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948"),
+		Destination: "xrb_13ezf4od79h1tgj9aiu4djzcmmguendtjfuhwfukhuucboua8cpoihmh8byo",
+		Balance:     balance,
+	}
+	hash, err := b.Hash()
+	require.Nil(t, err)
+	assert.Equal(t, "A170D51B94E00371ACE76E35AC81DC9405D5D04D4CEBC399AEACE07AE05DD293", hash.String())
+
+	b.Destination = "nano_invalid"
+	_, err = b.Hash()
+	assert.NotNil(t, err)
+}
+
+func TestBlockInfoUnmarshalJSON(t *testing.T) {
+	var info rpc.BlockInfo
+	require.Nil(t, json.Unmarshal([]byte(`{"contents":{"type":"send","balance":"ff"}}`), &info))
+	assert.Equal(t, "send", info.Subtype)
+	assert.True(t, info.IsSend())
+	assert.Equal(t, 0, info.Amount.Sign())
+	assert.Equal(t, 0, info.Balance.Sign())
+
+	info = rpc.BlockInfo{}
+	require.Nil(t, json.Unmarshal([]byte(`{"amount":"5","balance":"10","subtype":"receive",`+
+		`"contents":{"type":"state","balance":"10"}}`), &info))
+	assert.Equal(t, "receive", info.Subtype)
+	assert.False(t, info.IsSend())
+	assert.Equal(t, "5", info.Amount.String())
+
+	info = rpc.BlockInfo{}
+	require.Nil(t, json.Unmarshal([]byte(`{"contents":{"type":"state","balance":"10"}}`), &info))
+	assert.Equal(t, "", info.Subtype)
+}
+
+func TestBlockWork(t *testing.T) {
+	previous := hexString(testBlockInfoHash)
+	difficulty := hexString("ffff000000000000")
+	work, err := pow.Generate(previous, difficulty)
+	require.Nil(t, err)
+	b := rpc.Block{Type: "state", Previous: previous, Work: work}
+	assert.Len(t, b.WorkHex(), 16)
+	data, err := json.Marshal(&b)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), `"work":"`+b.WorkHex()+`"`)
+
+	var b2 rpc.Block
+	require.Nil(t, json.Unmarshal(data, &b2))
+	assert.Equal(t, b.WorkHex(), b2.WorkHex())
+	assert.True(t, pow.Validate(b2.Previous, b2.Work, difficulty))
+
+	b2 = rpc.Block{Work: hexString("3C82CC724905EE95")}
+	assert.Equal(t, "3c82cc724905ee95", b2.WorkHex())
+}
+
+func TestHexDataDifficulty(t *testing.T) {
+	d := rpc.HexDataFromUint64(0xfffffff800000000)
+	assert.Equal(t, "fffffff800000000", d.String())
+	assert.Equal(t, uint64(0xfffffff800000000), d.Uint64())
+	d2, err := rpc.ParseHexData("fffffe0000000000")
+	require.Nil(t, err)
+	assert.True(t, d.Uint64() > d2.Uint64())
+	_, err = rpc.ParseHexData("fffffe000000000g")
+	assert.NotNil(t, err)
+
+	data, err := json.Marshal(d)
+	require.Nil(t, err)
+	assert.Equal(t, `"fffffff800000000"`, string(data))
+	var d3 rpc.HexData
+	require.Nil(t, json.Unmarshal(data, &d3))
+	assert.Equal(t, d, d3)
+	assert.Equal(t, uint64(0x1234), rpc.HexData{0x12, 0x34}.Uint64())
+}
+
+func TestParseBlockSubtype(t *testing.T) {
+	for _, s := range []string{"send", "receive", "open", "change", "epoch"} {
+		subtype, err := rpc.ParseBlockSubtype(s)
+		require.Nil(t, err, s)
+		assert.Equal(t, rpc.BlockSubtype(s), subtype)
+	}
+	_, err := rpc.ParseBlockSubtype("recieve")
+	assert.EqualError(t, err, `unknown block subtype "recieve"`)
+}
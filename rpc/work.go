@@ -1,6 +1,10 @@
 package rpc
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
 
 // WorkCancel stops generating work for block.
 func (c *Client) WorkCancel(hash BlockHash) (err error) {
@@ -10,12 +14,47 @@ func (c *Client) WorkCancel(hash BlockHash) (err error) {
 
 // WorkGenerate generates work for block. hash is the frontier of the account
 // or in the case of an open block, the public key representation of the account.
-func (c *Client) WorkGenerate(hash BlockHash, difficulty HexData) (
-	work, difficulty2 HexData, multiplier float64, err error,
+// minDifficulty is the difficulty the work must at least meet, or nil for the
+// node's current network difficulty.
+//
+// Along with the work, in the byte order of a block's work field, the node
+// reports the difficulty the work actually achieves, which may exceed
+// minDifficulty, and the multiplier of that difficulty over the node's base
+// difficulty.
+func (c *Client) WorkGenerate(hash BlockHash, minDifficulty HexData) (
+	work, difficulty HexData, multiplier float64, err error,
 ) {
-	resp, err := c.send(map[string]interface{}{
-		"action": "work_generate", "hash": hash, "difficulty": difficulty,
-	})
+	return c.WorkGenerateWithOptions(hash, WorkGenerateOptions{Difficulty: minDifficulty})
+}
+
+// WorkGenerateOptions holds the optional parameters of work_generate.
+type WorkGenerateOptions struct {
+	// Difficulty is the minimum difficulty of the work. If nil, the node
+	// uses its current network difficulty.
+	Difficulty HexData
+	// Multiplier, if non-zero, requests work at this multiple of the
+	// node's base difficulty instead, overriding Difficulty.
+	Multiplier float64
+	// UsePeers asks the node to use its configured work peers.
+	UsePeers bool
+}
+
+// WorkGenerateWithOptions generates work for block like WorkGenerate, with
+// additional options.
+func (c *Client) WorkGenerateWithOptions(hash BlockHash, opts WorkGenerateOptions) (
+	work, difficulty HexData, multiplier float64, err error,
+) {
+	body := map[string]interface{}{"action": "work_generate", "hash": hash}
+	if opts.Difficulty != nil {
+		body["difficulty"] = opts.Difficulty
+	}
+	if opts.Multiplier != 0 {
+		body["multiplier"] = strconv.FormatFloat(opts.Multiplier, 'f', -1, 64)
+	}
+	if opts.UsePeers {
+		body["use_peers"] = true
+	}
+	resp, err := c.send(body)
 	if err != nil {
 		return
 	}
@@ -27,6 +66,39 @@ func (c *Client) WorkGenerate(hash BlockHash, difficulty HexData) (
 	return v.Work, v.Difficulty, v.Multiplier, err
 }
 
+// WorkPeerAdd adds a work peer for the node to use for work generation.
+func (c *Client) WorkPeerAdd(address string, port uint16) (err error) {
+	_, err = c.send(map[string]interface{}{
+		"action":  "work_peer_add",
+		"address": address,
+		"port":    strconv.Itoa(int(port)),
+	})
+	return
+}
+
+// WorkPeers returns the list of work peers used by the node.
+func (c *Client) WorkPeers() (peers []string, err error) {
+	resp, err := c.send(map[string]interface{}{"action": "work_peers"})
+	if err != nil {
+		return
+	}
+	var v struct {
+		// work_peers may come as an empty string instead of an array
+		WorkPeers json.RawMessage `json:"work_peers"`
+	}
+	if err = json.Unmarshal(resp, &v); err != nil {
+		return
+	}
+	_ = json.Unmarshal(v.WorkPeers, &peers)
+	return
+}
+
+// WorkPeersClear clears the node's work peers list.
+func (c *Client) WorkPeersClear() (err error) {
+	_, err = c.send(map[string]interface{}{"action": "work_peers_clear"})
+	return
+}
+
 // WorkValidate checks whether work is valid for block. Provides two values:
 // validAll is true if the work is valid at the current network difficulty
 // (work can be used for any block).
@@ -40,11 +112,44 @@ func (c *Client) WorkValidate(hash BlockHash, work HexData) (
 		return
 	}
 	var v struct {
-		ValidAll     int `json:"valid_all,string"`
-		ValidReceive int `json:"valid_receive,string"`
+		ValidAll     Uint64 `json:"valid_all"`
+		ValidReceive Uint64 `json:"valid_receive"`
 		Difficulty   HexData
 		Multiplier   float64 `json:",string"`
 	}
 	err = json.Unmarshal(resp, &v)
 	return v.ValidAll == 1, v.ValidReceive == 1, v.Difficulty, v.Multiplier, err
 }
+
+// DifficultyToMultiplier returns how many times harder work at difficulty is
+// to generate than work at base, as the node computes multipliers.
+func DifficultyToMultiplier(difficulty, base HexData) float64 {
+	return float64(-base.Uint64()) / float64(-difficulty.Uint64())
+}
+
+// MultiplierToDifficulty returns the difficulty of work that is mult times
+// as hard to generate as work at base. mult must be positive. A difficulty
+// too hard to represent is clamped to ffffffffffffffff, the hardest. A mult
+// of at least 1 never gives a difficulty below base, while for a mult below
+// 1 the difficulty is clamped to 0 once it would be easier than any work.
+func MultiplierToDifficulty(mult float64, base HexData) HexData {
+	b := base.Uint64()
+	// The distance of base from the hardest difficulty, which for a base
+	// of 0 doesn't fit in a uint64.
+	reverse := math.Exp2(64)
+	if b != 0 {
+		reverse = float64(-b)
+	}
+	reverse /= mult
+	var d uint64
+	switch {
+	case reverse < 1:
+		d = math.MaxUint64
+	case reverse < 1<<64:
+		d = -uint64(reverse)
+	}
+	if mult >= 1 && d < b {
+		d = b
+	}
+	return HexDataFromUint64(d)
+}
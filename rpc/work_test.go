@@ -0,0 +1,104 @@
+package rpc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkGenerateWithOptions(t *testing.T) {
+	c, body := newRecordingClient(t, `{"work":"2b3d689bbcb21dca","difficulty":"fffffff93c41ec94","multiplier":"1.182623871097636"}`)
+	hash := hexString(testBlockInfoHash)
+
+	work, difficulty, multiplier, err := c.WorkGenerateWithOptions(hash, rpc.WorkGenerateOptions{
+		Difficulty: hexString("fffffff800000000"),
+		UsePeers:   true,
+	})
+	require.Nil(t, err)
+	assertEqualBytes(t, "2b3d689bbcb21dca", work)
+	assertEqualBytes(t, "fffffff93c41ec94", difficulty)
+	assert.Equal(t, 1.182623871097636, multiplier)
+	assert.Equal(t, "work_generate", body()["action"])
+	assert.Equal(t, testBlockInfoHash, body()["hash"])
+	assert.Equal(t, "fffffff800000000", body()["difficulty"])
+	assert.Equal(t, true, body()["use_peers"])
+
+	_, _, _, err = c.WorkGenerateWithOptions(hash, rpc.WorkGenerateOptions{})
+	require.Nil(t, err)
+	assert.NotContains(t, body(), "difficulty")
+	assert.NotContains(t, body(), "use_peers")
+
+	_, _, _, err = c.WorkGenerateWithOptions(hash, rpc.WorkGenerateOptions{Multiplier: 8})
+	require.Nil(t, err)
+	assert.Equal(t, "8", body()["multiplier"])
+}
+
+func TestWorkGenerate(t *testing.T) {
+	c, body := newRecordingClient(t, `{"work":"2b3d689bbcb21dca","difficulty":"fffffff93c41ec94","multiplier":"1.182623871097636"}`)
+	work, difficulty, multiplier, err := c.WorkGenerate(hexString(testBlockInfoHash), hexString("fffffff800000000"))
+	require.Nil(t, err)
+	assertEqualBytes(t, "2b3d689bbcb21dca", work)
+	assertEqualBytes(t, "fffffff93c41ec94", difficulty)
+	assert.Equal(t, 1.182623871097636, multiplier)
+	assert.Equal(t, "fffffff800000000", body()["difficulty"])
+}
+
+func TestDifficultyMultiplier(t *testing.T) {
+	base := hexString("fffffff800000000")
+	assert.InDelta(t, 1.182623871097636, rpc.DifficultyToMultiplier(hexString("fffffff93c41ec94"), base), 1e-12)
+	assert.Equal(t, 1.0, rpc.DifficultyToMultiplier(base, base))
+	assert.Equal(t, 0.125, rpc.DifficultyToMultiplier(hexString("fffffe0000000000"), hexString("ffffffc000000000")))
+
+	assert.Equal(t, "fffffffe00000000", rpc.MultiplierToDifficulty(4, base).String())
+	assert.Equal(t, "fffffe0000000000", rpc.MultiplierToDifficulty(0.125, hexString("ffffffc000000000")).String())
+	assert.Equal(t, "fffffff800000000", rpc.MultiplierToDifficulty(1, base).String())
+	d := rpc.MultiplierToDifficulty(1.182623871097636, base)
+	assert.InDelta(t, 1.182623871097636, rpc.DifficultyToMultiplier(d, base), 1e-9)
+	assert.Equal(t, "ffffffffffffffff", rpc.MultiplierToDifficulty(1e30, base).String())
+}
+
+func TestMultiplierToDifficultyClamp(t *testing.T) {
+	for _, tt := range []struct {
+		mult           float64
+		base, expected string
+	}{
+		{1e30, "fffffff800000000", "ffffffffffffffff"},
+		{math.Inf(1), "0000000000000001", "ffffffffffffffff"},
+		{1, "ffffffffffffffff", "ffffffffffffffff"},
+		{1, "0000000000000001", "0000000000000001"},
+		{2, "0000000000000001", "8000000000000000"},
+		{1, "0000000000000000", "0000000000000000"},
+		{2, "0000000000000000", "8000000000000000"},
+		{0.5, "fffffff800000000", "fffffff000000000"},
+		{0.5, "0000000000000001", "0000000000000000"},
+		{1e-30, "ffffffffffffffff", "0000000000000000"},
+	} {
+		d := rpc.MultiplierToDifficulty(tt.mult, hexString(tt.base))
+		assert.Equal(t, tt.expected, d.String(), "%v %s", tt.mult, tt.base)
+	}
+}
+
+func TestWorkPeers(t *testing.T) {
+	c, body := newRecordingClient(t, `{"success":""}`)
+	require.Nil(t, c.WorkPeerAdd("::ffff:172.17.0.1", 7076))
+	assert.Equal(t, "work_peer_add", body()["action"])
+	assert.Equal(t, "::ffff:172.17.0.1", body()["address"])
+	assert.Equal(t, "7076", body()["port"])
+	require.Nil(t, c.WorkPeersClear())
+	assert.Equal(t, "work_peers_clear", body()["action"])
+
+	c, body = newRecordingClient(t, `{"work_peers":["::ffff:172.17.0.1:7076","::ffff:172.17.0.2:7076"]}`)
+	peers, err := c.WorkPeers()
+	require.Nil(t, err)
+	assert.Equal(t, "work_peers", body()["action"])
+	assert.Equal(t, []string{"::ffff:172.17.0.1:7076", "::ffff:172.17.0.2:7076"}, peers)
+
+	peers, err = newTestClient(t, `{"work_peers":""}`).WorkPeers()
+	require.Nil(t, err)
+	assert.Empty(t, peers)
+	_, err = newTestClient(t, `{"error":"RPC control is disabled"}`).WorkPeers()
+	assert.NotNil(t, err)
+}
@@ -3,13 +3,61 @@ package util
 import (
 	"encoding/base32"
 	"errors"
+	"hash"
+	"strings"
+	"sync"
 
 	"golang.org/x/crypto/blake2b"
 )
 
-// AddressToPubkey converts address to a pubkey.
-func AddressToPubkey(address string) (pubkey []byte, err error) {
-	err = errors.New("invalid address")
+var addressEncoding = base32.NewEncoding("13456789abcdefghijkmnopqrstuwxyz")
+
+var (
+	errInvalidAddress   = errors.New("invalid address")
+	errChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// addressCodec converts between addresses and pubkeys, reusing its hasher
+// and buffers across conversions.
+type addressCodec struct {
+	hash     hash.Hash
+	encoded  [56]byte
+	decoded  [35]byte
+	checksum [5]byte
+	// encodedChecksum is the base32 encoding of checksum.
+	encodedChecksum [8]byte
+}
+
+// codecPool holds codecs for reuse between conversions, which saves
+// allocating a hasher for every address.
+var codecPool = sync.Pool{New: func() interface{} {
+	c := new(addressCodec)
+	c.hash, _ = blake2b.New(5, nil)
+	return c
+}}
+
+func getAddressCodec() *addressCodec {
+	return codecPool.Get().(*addressCodec)
+}
+
+func (c *addressCodec) release() {
+	codecPool.Put(c)
+}
+
+// sum sets c.checksum to the checksum of pubkey.
+func (c *addressCodec) sum(pubkey []byte) {
+	c.hash.Reset()
+	c.hash.Write(pubkey)
+	var sum [5]byte
+	c.hash.Sum(sum[:0])
+	for i, b := range sum {
+		c.checksum[4-i] = b
+	}
+}
+
+// decode writes the pubkey of address to pubkey, which must be 32 bytes.
+func (c *addressCodec) decode(address string, pubkey []byte) (err error) {
+	err = errInvalidAddress
 	switch len(address) {
 	case 64:
 		if address[:4] != "xrb_" && address[:4] != "ban_" {
@@ -24,57 +72,114 @@ func AddressToPubkey(address string) (pubkey []byte, err error) {
 	default:
 		return
 	}
-	b32 := base32.NewEncoding("13456789abcdefghijkmnopqrstuwxyz")
-	if pubkey, err = b32.DecodeString("1111" + address[:52]); err != nil {
+	copy(c.encoded[:], "1111")
+	copy(c.encoded[4:], address[:52])
+	if _, err = addressEncoding.Decode(c.decoded[:], c.encoded[:]); err != nil {
 		return
 	}
-	pubkey = pubkey[3:]
-	checksum, err := checksum(pubkey)
-	if err != nil {
-		return
+	if c.decoded[2] != 0 {
+		// The padding bits of the first character must be zero, or an
+		// address other than the canonical one would decode to the pubkey.
+		return errInvalidAddress
 	}
-	if b32.EncodeToString(checksum) != address[52:] {
-		err = errors.New("checksum mismatch")
+	copy(pubkey, c.decoded[3:])
+	c.sum(pubkey)
+	addressEncoding.Encode(c.encodedChecksum[:], c.checksum[:])
+	if string(c.encodedChecksum[:]) != address[52:] {
+		err = errChecksumMismatch
 	}
 	return
 }
 
-// PubkeyToAddress converts pubkey to an address.
-func PubkeyToAddress(pubkey []byte) (address string, err error) {
+func (c *addressCodec) encode(prefix string, pubkey []byte) (address string, err error) {
 	if len(pubkey) != 32 {
 		return "", errors.New("invalid pubkey length")
 	}
-	checksum, err := checksum(pubkey)
-	if err != nil {
-		return
+	c.sum(pubkey)
+	c.decoded[0], c.decoded[1], c.decoded[2] = 0, 0, 0
+	copy(c.decoded[3:], pubkey)
+	addressEncoding.Encode(c.encoded[:], c.decoded[:])
+	addressEncoding.Encode(c.encodedChecksum[:], c.checksum[:])
+	return prefix + string(c.encoded[4:]) + string(c.encodedChecksum[:]), nil
+}
+
+// AddressToPubkey converts address to a pubkey.
+func AddressToPubkey(address string) (pubkey []byte, err error) {
+	c := getAddressCodec()
+	defer c.release()
+	pubkey = make([]byte, 32)
+	if err = c.decode(address, pubkey); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// AddressesToPubkeys converts addresses to pubkeys. It is faster than
+// calling AddressToPubkey for each address.
+func AddressesToPubkeys(addresses []string) (pubkeys [][]byte, err error) {
+	c := getAddressCodec()
+	defer c.release()
+	buf := make([]byte, 32*len(addresses))
+	pubkeys = make([][]byte, len(addresses))
+	for i, address := range addresses {
+		pubkeys[i] = buf[32*i : 32*(i+1) : 32*(i+1)]
+		if err = c.decode(address, pubkeys[i]); err != nil {
+			return nil, err
+		}
 	}
-	pubkey = append([]byte{0, 0, 0}, pubkey...)
-	b32 := base32.NewEncoding("13456789abcdefghijkmnopqrstuwxyz")
-	return "nano_" + b32.EncodeToString(pubkey)[4:] + b32.EncodeToString(checksum), nil
+	return
+}
+
+// PubkeyToAddress converts pubkey to an address.
+func PubkeyToAddress(pubkey []byte) (address string, err error) {
+	c := getAddressCodec()
+	defer c.release()
+	return c.encode("nano_", pubkey)
+}
+
+// PubkeysToAddresses converts pubkeys to addresses. It is faster than
+// calling PubkeyToAddress for each pubkey.
+func PubkeysToAddresses(pubkeys [][]byte) (addresses []string, err error) {
+	c := getAddressCodec()
+	defer c.release()
+	addresses = make([]string, len(pubkeys))
+	for i, pubkey := range pubkeys {
+		if addresses[i], err = c.encode("nano_", pubkey); err != nil {
+			return nil, err
+		}
+	}
+	return
 }
 
 // PubkeyToBananoAddress converts pubkey to a Banano address.
 func PubkeyToBananoAddress(pubkey []byte) (address string, err error) {
-	if len(pubkey) != 32 {
-		return "", errors.New("invalid pubkey length")
+	c := getAddressCodec()
+	defer c.release()
+	return c.encode("ban_", pubkey)
+}
+
+// NanoToBananoAddress converts a Nano address to the Banano address of the
+// same pubkey.
+func NanoToBananoAddress(address string) (banano string, err error) {
+	if !strings.HasPrefix(address, "nano_") && !strings.HasPrefix(address, "xrb_") {
+		return "", errors.New("not a nano address")
 	}
-	checksum, err := checksum(pubkey)
+	pubkey, err := AddressToPubkey(address)
 	if err != nil {
 		return
 	}
-	pubkey = append([]byte{0, 0, 0}, pubkey...)
-	b32 := base32.NewEncoding("13456789abcdefghijkmnopqrstuwxyz")
-	return "ban_" + b32.EncodeToString(pubkey)[4:] + b32.EncodeToString(checksum), nil
+	return PubkeyToBananoAddress(pubkey)
 }
 
-func checksum(pubkey []byte) (checksum []byte, err error) {
-	hash, err := blake2b.New(5, nil)
+// BananoToNanoAddress converts a Banano address to the Nano address of the
+// same pubkey.
+func BananoToNanoAddress(address string) (nano string, err error) {
+	if !strings.HasPrefix(address, "ban_") {
+		return "", errors.New("not a banano address")
+	}
+	pubkey, err := AddressToPubkey(address)
 	if err != nil {
 		return
 	}
-	hash.Write(pubkey)
-	for _, b := range hash.Sum(nil) {
-		checksum = append([]byte{b}, checksum...)
-	}
-	return
+	return PubkeyToAddress(pubkey)
 }
@@ -32,3 +32,97 @@ func TestPubkeyToBananoAddress(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, "ban_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx", address)
 }
+
+func TestNanoToBananoAddress(t *testing.T) {
+	address, err := util.NanoToBananoAddress("nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx")
+	require.Nil(t, err)
+	assert.Equal(t, "ban_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx", address)
+	address, err = util.BananoToNanoAddress(address)
+	require.Nil(t, err)
+	assert.Equal(t, "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx", address)
+
+	_, err = util.NanoToBananoAddress("ban_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx")
+	assert.NotNil(t, err)
+	_, err = util.BananoToNanoAddress("nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx")
+	assert.NotNil(t, err)
+	_, err = util.NanoToBananoAddress("nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5ry")
+	assert.NotNil(t, err)
+}
+
+func TestBananoAddressRoundTrip(t *testing.T) {
+	for address, pubkey := range map[string]string{
+		"ban_1bananobh5rat99qfgt1ptpieie5swmoth87thi74qgbfrij7dcgjiij94xr": "2514452a978f08d1cf76bb40b6ad064183cf275d3cc5d3e0515dc96e2112ad4e",
+		"ban_1ka1ium4pfue3uxtntqsrib8mumxgazsjf58gidh1xeo5te3whsq8z476goo": "490086e62b376c0efbaa6af9c41269ee7d723f98b4667416f075951e981e3f37",
+		"ban_1111111111111111111111111111111111111111111111111111hifc8npp": "0000000000000000000000000000000000000000000000000000000000000000",
+	} {
+		p, err := util.AddressToPubkey(address)
+		require.Nil(t, err, address)
+		assert.Equal(t, pubkey, hex.EncodeToString(p))
+		address2, err := util.PubkeyToBananoAddress(p)
+		require.Nil(t, err)
+		assert.Equal(t, address, address2)
+		nano, err := util.BananoToNanoAddress(address)
+		require.Nil(t, err)
+		address2, err = util.NanoToBananoAddress(nano)
+		require.Nil(t, err)
+		assert.Equal(t, address, address2)
+		pubkeys, err := util.AddressesToPubkeys([]string{address, nano})
+		require.Nil(t, err)
+		assert.Equal(t, pubkeys[0], pubkeys[1])
+	}
+}
+
+func TestInvalidBananoAddress(t *testing.T) {
+	for _, address := range []string{
+		// Checksum of another pubkey.
+		"ban_1bananobh5rat99qfgt1ptpieie5swmoth87thi74qgbfrij7dcgjiij94xs",
+		// Nonzero padding bits, decoding to the pubkey of the genesis account.
+		"ban_4bananobh5rat99qfgt1ptpieie5swmoth87thi74qgbfrij7dcgjiij94xr",
+		"bano_1bananobh5rat99qfgt1ptpieie5swmoth87thi74qgbfrij7dcgjiij94xr",
+		"BAN_1bananobh5rat99qfgt1ptpieie5swmoth87thi74qgbfrij7dcgjiij94xr",
+		"ban_1bananobh5rat99qfgt1ptpieie5swmoth87thi74qgbfrij7dcgjiij94x",
+		"ban_1bananobh5rat99qfgt1ptpieie5swmoth87thi74qgbfrij7dcgjiij94x0",
+	} {
+		_, err := util.AddressToPubkey(address)
+		assert.NotNil(t, err, address)
+		_, err = util.BananoToNanoAddress(address)
+		assert.NotNil(t, err, address)
+		_, err = util.AddressesToPubkeys([]string{address})
+		assert.NotNil(t, err, address)
+	}
+}
+
+func TestAddressesToPubkeys(t *testing.T) {
+	addresses := []string{
+		"nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx",
+		"nano_3i1aq1cchnmbn9x5rsbap8b15akfh7wj7pwskuzi7ahz8oq6cobd99d4r3b7",
+	}
+	pubkeys, err := util.AddressesToPubkeys(addresses)
+	require.Nil(t, err)
+	require.Len(t, pubkeys, 2)
+	assert.Equal(t, "3068bb1ca04525bb0e416c485fe6a67fd52540227d267cc8b6e8da958a7fa039", hex.EncodeToString(pubkeys[0]))
+	assert.Equal(t, "c008b814a7d269a1fa3c6528b19201a24d797912db9996ff02a1ff356e45552b", hex.EncodeToString(pubkeys[1]))
+	addresses2, err := util.PubkeysToAddresses(pubkeys)
+	require.Nil(t, err)
+	assert.Equal(t, addresses, addresses2)
+
+	_, err = util.AddressesToPubkeys(append(addresses, "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5ry"))
+	assert.NotNil(t, err)
+	_, err = util.PubkeysToAddresses([][]byte{make([]byte, 31)})
+	assert.NotNil(t, err)
+}
+
+func BenchmarkPubkeyToAddress(b *testing.B) {
+	pubkey, _ := hex.DecodeString("3068bb1ca04525bb0e416c485fe6a67fd52540227d267cc8b6e8da958a7fa039")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		util.PubkeyToAddress(pubkey)
+	}
+}
+
+func BenchmarkAddressToPubkey(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		util.AddressToPubkey("nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx")
+	}
+}
@@ -36,3 +36,35 @@ func (n NanoAmount) String() string {
 	s := r.FloatString(30)
 	return s[:len(s)-24]
 }
+
+// BananoAmount wraps a raw amount of Banano.
+type BananoAmount struct {
+	Raw *big.Int
+}
+
+func (BananoAmount) exp() *big.Int {
+	x := big.NewInt(10)
+	return x.Exp(x, big.NewInt(29), nil)
+}
+
+// BananoAmountFromString parses BANANO amounts in strings.
+func BananoAmountFromString(s string) (n BananoAmount, err error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		err = errors.New("unable to parse banano amount")
+		return
+	}
+	r = r.Mul(r, new(big.Rat).SetInt(n.exp()))
+	if !r.IsInt() {
+		err = errors.New("unable to parse banano amount")
+		return
+	}
+	n.Raw = r.Num()
+	return
+}
+
+func (n BananoAmount) String() string {
+	r := new(big.Rat).SetFrac(n.Raw, n.exp())
+	s := r.FloatString(29)
+	return s[:len(s)-23]
+}
@@ -26,3 +26,20 @@ func TestNanoAmount(t *testing.T) {
 		require.NotNil(t, err)
 	}
 }
+
+func TestBananoAmount(t *testing.T) {
+	for _, s := range []string{
+		"1000.000000",
+		"0.100000",
+		"0.000001",
+	} {
+		n, err := util.BananoAmountFromString(s)
+		require.Nil(t, err)
+		assert.Equal(t, s, n.String())
+	}
+	n, err := util.BananoAmountFromString("1")
+	require.Nil(t, err)
+	assert.Equal(t, "100000000000000000000000000000", n.Raw.String())
+	_, err = util.BananoAmountFromString("0.000000000000000000000000000001")
+	require.NotNil(t, err)
+}
@@ -1,8 +1,10 @@
 package wallet
 
 import (
-	"encoding/hex"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/hectorchu/gonano/rpc"
@@ -37,32 +39,170 @@ func (a *Account) Balance() (balance, pending *big.Int, err error) {
 	return &b.Int, &p.Int, nil
 }
 
-// Send sends an amount to an account.
+// Info gets the account info for the account.
+func (a *Account) Info() (info rpc.AccountInfo, err error) {
+	return a.w.RPC.AccountInfo(a.address)
+}
+
+// IsOpened reports whether the account has been opened by receiving to it.
+func (a *Account) IsOpened() (bool, error) {
+	return a.w.RPC.AccountExists(a.address)
+}
+
+// ConfirmedBalance gets the balance of the account as of its latest
+// confirmed block. Nodes that don't report the confirmed balance directly
+// are handled by looking up the block at the confirmation height.
+func (a *Account) ConfirmedBalance() (balance *big.Int, err error) {
+	info, err := a.accountInfo()
+	if err != nil {
+		return
+	}
+	return a.confirmedBalance(info)
+}
+
+func (a *Account) confirmedBalance(info rpc.AccountInfo) (balance *big.Int, err error) {
+	if info.ConfirmedBalance != nil {
+		return &info.ConfirmedBalance.Int, nil
+	}
+	if info.ConfirmationHeight == 0 {
+		return new(big.Int), nil
+	}
+	block, err := a.w.RPC.BlockInfo(info.ConfirmationHeightFrontier)
+	if err != nil {
+		return
+	}
+	return &block.Balance.Int, nil
+}
+
+// SpendableBalance gets the balance of the account that can be sent without
+// building on unconfirmed blocks. This is the confirmed balance, less any
+// sends that aren't confirmed yet, while unconfirmed receives are left out.
+// To spend the whole balance, wait for the frontier of the account to be
+// confirmed with Wallet.WaitForConfirmation.
+func (a *Account) SpendableBalance() (balance *big.Int, err error) {
+	info, err := a.accountInfo()
+	if err != nil {
+		return
+	}
+	if balance, err = a.confirmedBalance(info); err != nil {
+		return
+	}
+	if info.Balance.Cmp(balance) < 0 {
+		balance = &info.Balance.Int
+	}
+	return
+}
+
+// Send sends an amount to an account. With Wallet.RetryStaleSend, a send
+// the node rejects as a fork or an old block is built again once on the
+// account's new frontier. A gap previous is returned as is, since it means
+// the node hasn't seen the frontier yet rather than that it is stale.
 func (a *Account) Send(account string, amount *big.Int) (hash rpc.BlockHash, err error) {
 	block, err := a.SendBlock(account, amount)
 	if err != nil {
 		return
 	}
-	if block.Work, err = a.w.workGenerate(block.Previous); err != nil {
+	hash, err = a.publishSend(block)
+	if !a.w.RetryStaleSend || !errors.Is(err, rpc.ErrFork) && !errors.Is(err, rpc.ErrOldBlock) {
 		return
 	}
-	return a.w.RPC.Process(block, "send")
+	retry, err2 := a.SendBlock(account, amount)
+	if err2 != nil || bytes.Equal(retry.Previous, block.Previous) {
+		// The frontier wasn't stale, so building again won't help.
+		return
+	}
+	return a.publishSend(retry)
+}
+
+// SendAndConfirm sends an amount to an account and waits for the send to be
+// confirmed, as Wallet.WaitForConfirmation. The hash is returned even if
+// waiting for the confirmation fails, since the block was published.
+func (a *Account) SendAndConfirm(ctx context.Context, account string, amount *big.Int) (hash rpc.BlockHash, err error) {
+	if hash, err = a.Send(account, amount); err != nil {
+		return
+	}
+	err = a.w.WaitForConfirmation(ctx, hash)
+	return
+}
+
+// FindSend looks for a send of amount to account among the latest depth
+// blocks of the account's history, returning its hash, or nil if there is
+// none.
+func (a *Account) FindSend(account string, amount *big.Int, depth int64) (hash rpc.BlockHash, err error) {
+	pubkey, err := util.AddressToPubkey(account)
+	if err != nil {
+		return
+	}
+	history, _, err := a.w.RPC.AccountHistory(a.address, depth, nil)
+	if err != nil {
+		return
+	}
+	for _, h := range history {
+		if h.Type != "send" || h.Amount.Cmp(amount) != 0 {
+			continue
+		}
+		if p, err := util.AddressToPubkey(h.Account); err == nil && bytes.Equal(p, pubkey) {
+			return h.Hash, nil
+		}
+	}
+	return
+}
+
+// SendOnce sends an amount to an account unless FindSend finds such a send
+// within the latest depth blocks, in which case its hash is returned instead.
+// It is for retrying a send whose outcome is unknown, such as after a timeout,
+// without sending twice. depth must cover every block the account could have
+// created since the first attempt, and two intended sends of the same amount
+// to the same account are taken to be one, so callers should make amounts
+// unique or track sends themselves. Where the signed block of the first
+// attempt is kept, publishing that same block again is safer still, as the
+// node accepts it only once.
+func (a *Account) SendOnce(account string, amount *big.Int, depth int64) (hash rpc.BlockHash, err error) {
+	if hash, err = a.FindSend(account, amount, depth); err != nil || hash != nil {
+		return
+	}
+	return a.Send(account, amount)
+}
+
+// SendAll sends the entire balance of the account to an account. If the
+// account has no balance then no block is created and a nil hash is returned.
+func (a *Account) SendAll(account string) (hash rpc.BlockHash, err error) {
+	info, err := a.accountInfo()
+	if err != nil || info.Balance.Sign() == 0 {
+		return
+	}
+	block, err := a.sendBlock(info, account, &info.Balance.Int)
+	if err != nil {
+		return
+	}
+	return a.publishSend(block)
+}
+
+func (a *Account) publishSend(block *rpc.Block) (hash rpc.BlockHash, err error) {
+	if block.Work, err = a.w.workGenerate(context.Background(), block.Previous); err != nil {
+		return
+	}
+	return a.w.process(block, rpc.SubtypeSend)
 }
 
 // SendBlock generates a signed send block.
 func (a *Account) SendBlock(account string, amount *big.Int) (block *rpc.Block, err error) {
-	link, err := util.AddressToPubkey(account)
+	info, err := a.w.RPC.AccountInfo(a.address)
 	if err != nil {
 		return
 	}
-	info, err := a.w.RPC.AccountInfo(a.address)
+	return a.sendBlock(info, account, amount)
+}
+
+func (a *Account) sendBlock(info rpc.AccountInfo, account string, amount *big.Int) (block *rpc.Block, err error) {
+	link, err := util.AddressToPubkey(account)
 	if err != nil {
 		return
 	}
 	if a.representative == "" {
 		a.representative = info.Representative
 	}
-	if info.Balance.Sub(&info.Balance.Int, amount).Sign() < 0 {
+	if info.Balance = info.Balance.Minus(amount); info.Balance.Sign() < 0 {
 		return nil, errors.New("insufficient funds")
 	}
 	block = &rpc.Block{
@@ -84,18 +224,53 @@ type SendDestination struct {
 
 // Send sends multiple amounts to multiple accounts. The caller must guarantee that no new blocks are created for this account until this function returns
 func (a *Account) SendMultiple(destinations []SendDestination) (hashes []rpc.BlockHash, err error) {
+	return a.SendMultipleWithOptions(destinations, SendMultipleOptions{})
+}
+
+// SendMultipleOptions are the options for SendMultipleWithOptions.
+type SendMultipleOptions struct {
+	// WaitForConfirmation waits for each block to be confirmed before the
+	// next one is published.
+	WaitForConfirmation bool
+	// Progress, if set, is called with the index of the destination once its
+	// block is published, and again with confirmed set once it is confirmed
+	// if WaitForConfirmation is set.
+	Progress func(index int, hash rpc.BlockHash, confirmed bool)
+}
+
+// SendMultipleWithOptions is like SendMultiple, with progress reporting and
+// optionally waiting for confirmations.
+func (a *Account) SendMultipleWithOptions(destinations []SendDestination, opts SendMultipleOptions) (hashes []rpc.BlockHash, err error) {
+	return a.SendMultipleContext(context.Background(), destinations, opts)
+}
+
+// SendMultipleContext is like SendMultipleWithOptions, but stops once ctx is
+// done, including while waiting for a confirmation, returning ctx.Err().
+// Blocks published before then stay published.
+func (a *Account) SendMultipleContext(ctx context.Context, destinations []SendDestination, opts SendMultipleOptions) (hashes []rpc.BlockHash, err error) {
 	blocks, err := a.SendBlocks(destinations)
 	if err != nil {
 		return
 	}
 	blocksWithWorkChan := make(chan *rpc.Block, len(destinations))
-	errChan := make(chan error)
+	// errChan is buffered and done is closed on return, so that the work
+	// goroutine never blocks or keeps generating work after an early return.
+	errChan := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
 		for i := range blocks {
-			if blocks[i].Work, err = a.w.workGenerate(blocks[i].Previous); err != nil {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			work, err := a.w.workGenerate(ctx, blocks[i].Previous)
+			if err != nil {
 				errChan <- err
 				return
 			}
+			blocks[i].Work = work
 			blocksWithWorkChan <- blocks[i]
 		}
 		close(blocksWithWorkChan)
@@ -107,13 +282,26 @@ func (a *Account) SendMultiple(destinations []SendDestination) (hashes []rpc.Blo
 			if !ok {
 				return hashes, nil
 			}
-			hash, err := a.w.RPC.Process(block, "send")
+			hash, err := a.w.process(block, rpc.SubtypeSend)
 			if err != nil {
 				return nil, err
 			}
+			if opts.Progress != nil {
+				opts.Progress(len(hashes), hash, false)
+			}
+			if opts.WaitForConfirmation {
+				if err = a.w.WaitForConfirmation(ctx, hash); err != nil {
+					return nil, err
+				}
+				if opts.Progress != nil {
+					opts.Progress(len(hashes), hash, true)
+				}
+			}
 			hashes = append(hashes, hash)
 		case err := <-errChan:
 			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
 }
@@ -137,7 +325,7 @@ func (a *Account) SendBlocks(destinations []SendDestination) ([]*rpc.Block, erro
 		if a.representative == "" {
 			a.representative = info.Representative
 		}
-		if info.Balance.Sub(&info.Balance.Int, destination.Amount).Sign() < 0 {
+		if info.Balance = info.Balance.Minus(destination.Amount); info.Balance.Sign() < 0 {
 			return nil, errors.New("insufficient funds")
 		}
 		block := &rpc.Block{
@@ -145,7 +333,7 @@ func (a *Account) SendBlocks(destinations []SendDestination) ([]*rpc.Block, erro
 			Account:        a.address,
 			Previous:       frontier,
 			Representative: a.representative,
-			Balance:        &rpc.RawAmount{Int: *new(big.Int).Set(&info.Balance.Int)},
+			Balance:        info.Balance,
 			Link:           link,
 		}
 		err = a.w.impl.signBlock(a, block)
@@ -164,63 +352,137 @@ func (a *Account) SendBlocks(destinations []SendDestination) ([]*rpc.Block, erro
 
 // ReceivePendings pockets all pending amounts.
 func (a *Account) ReceivePendings(threshold *big.Int) (err error) {
-	pendings, err := a.w.RPC.AccountsPending([]string{a.address}, -1,
-		&rpc.RawAmount{Int: *threshold})
+	_, err = a.ReceiveAndReturnPendings(threshold)
+	return
+}
+
+// ReceivePendingsWithRep pockets all pending amounts as ReceivePendings. If
+// the account isn't opened yet, its open block is given representative, as
+// are its later blocks, saving a change block afterwards. The representative
+// of an opened account is left unchanged.
+func (a *Account) ReceivePendingsWithRep(threshold *big.Int, representative string) (err error) {
+	if _, err = util.AddressToPubkey(representative); err != nil {
+		return
+	}
+	info, err := a.accountInfo()
 	if err != nil {
 		return
 	}
-	return a.receivePendings(pendings[a.address])
+	if info.Frontier == nil {
+		a.representative = representative
+	}
+	return a.ReceivePendings(threshold)
 }
 
 // ReceiveAndReturnPendings pockets all pending amounts and returns the list of sources.
 func (a *Account) ReceiveAndReturnPendings(threshold *big.Int) (receivedPendings rpc.HashToPendingMap, err error) {
+	threshold = a.w.receiveThreshold(threshold)
 	pendings, err := a.w.RPC.AccountsPending([]string{a.address}, -1,
 		&rpc.RawAmount{Int: *threshold})
 	if err != nil {
 		return
 	}
-	receivedPendings = pendings[a.address]
-	err = a.receivePendings(receivedPendings)
+	receivedPendings = filterPendings(pendings[a.address], threshold)
+	err = a.receivePendings(context.Background(), receivedPendings)
 	return
 }
 
-// ReceivePending pockets the specified link block.
+// filterPendings returns the pendings of at least threshold, in case the
+// node didn't apply the threshold itself.
+func filterPendings(pendings rpc.HashToPendingMap, threshold *big.Int) rpc.HashToPendingMap {
+	if threshold.Sign() == 0 {
+		return pendings
+	}
+	filtered := make(rpc.HashToPendingMap, len(pendings))
+	for hash, pending := range pendings {
+		if pending.Amount.Cmp(threshold) >= 0 {
+			filtered[hash] = pending
+		}
+	}
+	return filtered
+}
+
+// ReceivePending pockets the specified link block. The link must be a send,
+// whose amount is added to the balance of the account.
 func (a *Account) ReceivePending(link rpc.BlockHash) (hash rpc.BlockHash, err error) {
-	info, err := a.w.RPC.AccountInfo(a.address)
+	info, err := a.accountInfo()
 	if err != nil {
-		info.Balance = &rpc.RawAmount{}
+		return
 	}
 	block, err := a.w.RPC.BlockInfo(link)
 	if err != nil {
 		return
 	}
-	info.Balance.Add(&info.Balance.Int, &block.Amount.Int)
-	return a.receivePending(info, link)
+	if !block.IsSend() {
+		return nil, errors.New("link is not a send block")
+	}
+	info.Balance = info.Balance.Plus(&block.Amount.Int)
+	return a.receivePending(context.Background(), info, link)
+}
+
+// ReceivePendingAmount pockets the specified link block, which the caller
+// knows to be for amount, saving the lookup done by ReceivePending.
+func (a *Account) ReceivePendingAmount(link rpc.BlockHash, amount *big.Int) (hash rpc.BlockHash, err error) {
+	info, err := a.accountInfo()
+	if err != nil {
+		return
+	}
+	info.Balance = info.Balance.Plus(amount)
+	return a.receivePending(context.Background(), info, link)
 }
 
-func (a *Account) receivePendings(pendings rpc.HashToPendingMap) (err error) {
+func (a *Account) receivePendings(ctx context.Context, pendings rpc.HashToPendingMap) (err error) {
 	if len(pendings) == 0 {
 		return
 	}
-	info, err := a.w.RPC.AccountInfo(a.address)
+	info, err := a.accountInfo()
 	if err != nil {
-		info.Balance = &rpc.RawAmount{}
+		return
 	}
 	for hash, pending := range pendings {
-		var link rpc.BlockHash
-		if link, err = hex.DecodeString(hash); err != nil {
+		if err = ctx.Err(); err != nil {
 			return
 		}
-		info.Balance.Add(&info.Balance.Int, &pending.Amount.Int)
-		if info.Frontier, err = a.receivePending(info, link); err != nil {
+		var link rpc.BlockHash
+		if link, err = rpc.ParseBlockHash(hash); err != nil {
 			return
 		}
+		info.Balance = info.Balance.Plus(&pending.Amount.Int)
+		hash, err := a.receivePending(ctx, info, link)
+		if errors.Is(err, rpc.ErrOldBlock) || errors.Is(err, rpc.ErrUnreceivable) {
+			// Already pocketed, e.g. by an earlier run that was interrupted.
+			if info, err = a.accountInfo(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Frontier == nil {
+			// Accounts are opened at the current epoch.
+			info.AccountVersion = 2
+		}
+		info.Frontier = hash
 	}
 	return
 }
 
-func (a *Account) receivePending(info rpc.AccountInfo, link rpc.BlockHash) (hash rpc.BlockHash, err error) {
+// accountInfo gets the account info, treating an unopened account as having
+// a zero balance. Any other error is returned, so that a transient failure is
+// never mistaken for an account that needs an open block.
+func (a *Account) accountInfo() (info rpc.AccountInfo, err error) {
+	if info, err = a.w.RPC.AccountInfo(a.address); errors.Is(err, rpc.ErrAccountNotFound) {
+		info, err = rpc.AccountInfo{Balance: &rpc.RawAmount{}}, nil
+	}
+	return
+}
+
+func (a *Account) receivePending(ctx context.Context, info rpc.AccountInfo, link rpc.BlockHash) (hash rpc.BlockHash, err error) {
 	workHash := info.Frontier
+	// Nodes that don't report the account version are taken to be at epoch
+	// 1, which only costs unneeded work.
+	preEpoch2 := info.Frontier != nil && info.AccountVersion < 2
 	if info.Frontier == nil {
 		info.Frontier = make(rpc.BlockHash, 32)
 		workHash = a.pubkey
@@ -228,7 +490,7 @@ func (a *Account) receivePending(info rpc.AccountInfo, link rpc.BlockHash) (hash
 	if a.representative == "" {
 		a.representative = info.Representative
 		if a.representative == "" {
-			a.representative = "nano_3gonano8jnse4zm65jaiki9tk8ry4jtgc1smarinukho6fmbc45k3icsh6en"
+			a.representative = DefaultRepresentative
 		}
 	}
 	block := &rpc.Block{
@@ -242,10 +504,10 @@ func (a *Account) receivePending(info rpc.AccountInfo, link rpc.BlockHash) (hash
 	if err = a.w.impl.signBlock(a, block); err != nil {
 		return
 	}
-	if block.Work, err = a.w.workGenerateReceive(workHash); err != nil {
+	if block.Work, err = a.w.workGenerateReceive(ctx, workHash, preEpoch2); err != nil {
 		return
 	}
-	return a.w.RPC.Process(block, "receive")
+	return a.w.process(block, rpc.SubtypeReceive)
 }
 
 // SetRep sets the account's representative for future blocks.
@@ -274,11 +536,60 @@ func (a *Account) ChangeRep(representative string) (hash rpc.BlockHash, err erro
 	if err = a.w.impl.signBlock(a, block); err != nil {
 		return
 	}
-	if block.Work, err = a.w.workGenerate(info.Frontier); err != nil {
+	if block.Work, err = a.w.workGenerate(context.Background(), info.Frontier); err != nil {
 		return
 	}
-	if hash, err = a.w.RPC.Process(block, "change"); err == nil {
+	if hash, err = a.w.process(block, rpc.SubtypeChange); err == nil && !a.w.DryRun {
 		a.representative = representative
 	}
 	return
 }
+
+// ForkError is returned when publishing a block that competes with a block
+// already in the ledger for the same previous block.
+type ForkError struct {
+	Previous rpc.BlockHash
+	// Block is the hash of the block that was rejected and Existing is the
+	// hash of the block the node already has in its place.
+	Block, Existing rpc.BlockHash
+}
+
+func (e *ForkError) Error() string {
+	return fmt.Sprintf("fork at %s: %s competes with %s", e.Previous, e.Block, e.Existing)
+}
+
+// Unwrap allows errors.Is(err, rpc.ErrFork) to match a *ForkError.
+func (e *ForkError) Unwrap() error {
+	return rpc.ErrFork
+}
+
+func (w *Wallet) forkError(block *rpc.Block) error {
+	e := &ForkError{Previous: block.Previous}
+	e.Block, _ = block.Hash()
+	if new(big.Int).SetBytes(block.Previous).Sign() == 0 {
+		if info, err := w.RPC.AccountInfo(block.Account); err == nil {
+			e.Existing = info.OpenBlock
+		}
+	} else if blocks, err := w.RPC.Successors(block.Previous, 2); err == nil && len(blocks) == 2 {
+		e.Existing = blocks[1]
+	}
+	return e
+}
+
+// ResolveFork asks the node to confirm the account's frontier, so that an
+// election settles which side of a fork the network keeps, and then
+// republishes the frontier. Blocks for the account can be created again once
+// the fork is resolved, as they will build on the winning block.
+func (a *Account) ResolveFork() (frontier rpc.BlockHash, err error) {
+	info, err := a.w.RPC.AccountInfo(a.address)
+	if err != nil {
+		return
+	}
+	if _, err = a.w.RPC.BlockConfirm(info.Frontier); err != nil {
+		return
+	}
+	if err = a.w.RPC.RepublishBlock(info.Frontier); err != nil {
+		return
+	}
+	return info.Frontier, nil
+}
@@ -0,0 +1,539 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode answers rpc requests with canned responses keyed by action.
+type fakeNode struct {
+	mutex     sync.Mutex
+	responses map[string]string
+	actions   []string
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var v struct {
+		Action string
+		Block  *rpc.Block
+	}
+	json.NewDecoder(r.Body).Decode(&v)
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.actions = append(n.actions, v.Action)
+	resp, ok := n.responses[v.Action]
+	if !ok && v.Action == "process" && v.Block != nil {
+		// Process blocks successfully unless told otherwise.
+		if hash, err := v.Block.Hash(); err == nil {
+			resp, ok = `{"hash":"`+hash.String()+`"}`, true
+		}
+	}
+	if !ok {
+		resp = `{"error":"Unknown command"}`
+	}
+	w.Write([]byte(resp))
+}
+
+func (n *fakeNode) called(action string) (count int) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, a := range n.actions {
+		if a == action {
+			count++
+		}
+	}
+	return
+}
+
+func newTestWallet(t *testing.T, responses map[string]string) (w *Wallet, node *fakeNode) {
+	node = &fakeNode{responses: responses}
+	if _, ok := responses["work_generate"]; !ok {
+		responses["work_generate"] = `{"work":"0000000000000000"}`
+	}
+	s := httptest.NewServer(node)
+	t.Cleanup(s.Close)
+	seed, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	w, err := NewWallet(seed)
+	require.Nil(t, err)
+	w.RPC.URL = s.URL
+	w.RPCWork.URL = s.URL
+	return
+}
+
+const (
+	testFrontier    = "8C1B5D4BBE27F05C7A888D1E691A07C550A81AFEE16D913EE21E1093888B82FD"
+	testDestination = "nano_1e5aqegc1jb7qe964u4adzmcezyo6o146zb8hm6dft8tkp79za3sxwjym5rx"
+)
+
+func TestDryRunSend(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000",` +
+			`"representative":"nano_1natrium1o3z5519ifou7xii8crpxpk8y65qmkih8e8bpsjri651oza8imdd"}`,
+	})
+	w.DryRun = true
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	hash, err := a.Send(testDestination, big.NewInt(400))
+	require.Nil(t, err)
+	assert.Equal(t, 0, node.called("process"))
+
+	blocks := w.DryRunBlocks()
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "600", blocks[0].Balance.String())
+	assert.Equal(t, testFrontier, blocks[0].Previous.String())
+	assert.NotEmpty(t, blocks[0].Signature)
+	assert.NotEmpty(t, blocks[0].Work)
+	expected, err := blocks[0].Hash()
+	require.Nil(t, err)
+	assert.Equal(t, expected, hash)
+	assert.Empty(t, w.DryRunBlocks())
+}
+
+func TestReceiveAccountInfoError(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"error":"Unable to connect"}`,
+		"block_info":   `{"amount":"1000","subtype":"send"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	_, err = a.ReceivePending(make(rpc.BlockHash, 32))
+	assert.Equal(t, rpc.Error("Unable to connect"), err)
+	assert.Equal(t, 0, node.called("process"))
+}
+
+func TestReceiveUnopened(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"error":"Account not found"}`,
+		"block_info":   `{"amount":"1000","subtype":"send"}`,
+	})
+	w.DryRun = true
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	_, err = a.ReceivePending(make(rpc.BlockHash, 32))
+	require.Nil(t, err)
+	blocks := w.DryRunBlocks()
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "1000", blocks[0].Balance.String())
+	assert.Equal(t, make(rpc.BlockHash, 32), blocks[0].Previous)
+}
+
+func TestConfirmedBalance(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"balance":"1000","confirmation_height":"3","confirmed_balance":"600"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	balance, err := a.ConfirmedBalance()
+	require.Nil(t, err)
+	assert.Equal(t, "600", balance.String())
+
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"balance":"1000","confirmation_height":"3",` +
+			`"confirmation_height_frontier":"` + testFrontier + `"}`,
+		"block_info": `{"balance":"700"}`,
+	})
+	a, err = w.NewAccount(nil)
+	require.Nil(t, err)
+	balance, err = a.ConfirmedBalance()
+	require.Nil(t, err)
+	assert.Equal(t, "700", balance.String())
+	assert.Equal(t, 1, node.called("block_info"))
+
+	w, _ = newTestWallet(t, map[string]string{"account_info": `{"error":"Account not found"}`})
+	a, err = w.NewAccount(nil)
+	require.Nil(t, err)
+	balance, err = a.ConfirmedBalance()
+	require.Nil(t, err)
+	assert.Equal(t, 0, balance.Sign())
+}
+
+func TestSpendableBalance(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"balance":"1000","confirmation_height":"3","confirmed_balance":"600"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	balance, err := a.SpendableBalance()
+	require.Nil(t, err)
+	assert.Equal(t, "600", balance.String())
+
+	node.responses["account_info"] = `{"balance":"400","confirmation_height":"3","confirmed_balance":"600"}`
+	balance, err = a.SpendableBalance()
+	require.Nil(t, err)
+	assert.Equal(t, "400", balance.String())
+}
+
+func TestReceiveAlreadyPocketed(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"process":      `{"error":"Unreceivable"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	err = a.receivePendings(context.Background(), rpc.HashToPendingMap{
+		"96D8422D1CB676EF1B62A313865626A7725C3B9BB5B875601A1460ACF30B5322": {Amount: &rpc.RawAmount{}},
+		testFrontier: {Amount: &rpc.RawAmount{}},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 2, node.called("process"))
+	assert.Equal(t, 3, node.called("account_info"))
+
+	node.responses["process"] = `{"error":"Fork"}`
+	err = a.receivePendings(context.Background(), rpc.HashToPendingMap{testFrontier: {Amount: &rpc.RawAmount{}}})
+	assert.True(t, errors.Is(err, rpc.ErrFork))
+}
+
+func TestSendFork(t *testing.T) {
+	const existing = "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948"
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000",` +
+			`"representative":"nano_1natrium1o3z5519ifou7xii8crpxpk8y65qmkih8e8bpsjri651oza8imdd"}`,
+		"process":    `{"error":"Fork"}`,
+		"successors": `{"blocks":["` + testFrontier + `","` + existing + `"]}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	_, err = a.Send(testDestination, big.NewInt(400))
+	require.True(t, errors.Is(err, rpc.ErrFork))
+	var fork *ForkError
+	require.True(t, errors.As(err, &fork))
+	assert.Equal(t, testFrontier, fork.Previous.String())
+	assert.Equal(t, existing, fork.Existing.String())
+	assert.NotNil(t, fork.Block)
+}
+
+func TestAccountInfo(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","block_count":"3"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	info, err := a.Info()
+	require.Nil(t, err)
+	assert.Equal(t, testFrontier, info.Frontier.String())
+	assert.Equal(t, "1000", info.Balance.String())
+	assert.Equal(t, rpc.Uint64(3), info.BlockCount)
+}
+
+func TestReceivePendingAmount(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	w.DryRun = true
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	link, _ := rpc.ParseBlockHash(testFrontier)
+	_, err = a.ReceivePendingAmount(link, big.NewInt(500))
+	require.Nil(t, err)
+	assert.Equal(t, 0, node.called("block_info"))
+	blocks := w.DryRunBlocks()
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "1500", blocks[0].Balance.String())
+}
+
+func TestSendMultipleWithOptions(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"block_info":   `{"confirmed":"true"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	var progress []string
+	hashes, err := a.SendMultipleWithOptions([]SendDestination{
+		{testDestination, big.NewInt(100)},
+		{testDestination, big.NewInt(200)},
+	}, SendMultipleOptions{
+		WaitForConfirmation: true,
+		Progress: func(index int, hash rpc.BlockHash, confirmed bool) {
+			progress = append(progress, fmt.Sprint(index, confirmed))
+		},
+	})
+	require.Nil(t, err)
+	assert.Len(t, hashes, 2)
+	assert.Equal(t, []string{"0 false", "0 true", "1 false", "1 true"}, progress)
+	assert.Equal(t, 2, node.called("block_info"))
+}
+
+func TestSendMultipleContext(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"block_info":   `{"confirmed":"false"}`,
+	})
+	w.ConfirmationPollInterval = time.Millisecond
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = a.SendMultipleContext(ctx, []SendDestination{
+		{testDestination, big.NewInt(100)},
+		{testDestination, big.NewInt(200)},
+	}, SendMultipleOptions{WaitForConfirmation: true})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, node.called("process"))
+}
+
+func TestBeforeBroadcast(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	limit := errors.New("over the spend limit")
+	w.BeforeBroadcast = func(block *rpc.Block, subtype rpc.BlockSubtype) error {
+		assert.Equal(t, rpc.SubtypeSend, subtype)
+		assert.NotEmpty(t, block.Signature)
+		assert.NotEmpty(t, block.Work)
+		if block.Balance.Cmp(big.NewInt(500)) < 0 {
+			return limit
+		}
+		return nil
+	}
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	_, err = a.Send(testDestination, big.NewInt(100))
+	require.Nil(t, err)
+	_, err = a.Send(testDestination, big.NewInt(600))
+	assert.Equal(t, limit, err)
+	assert.Equal(t, 1, node.called("process"))
+}
+
+func TestProcessHashMismatch(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"process":      `{"hash":"` + testFrontier + `"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	_, err = a.Send(testDestination, big.NewInt(100))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), testFrontier)
+}
+
+func TestSendMultiple(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	destinations := make([]SendDestination, 50)
+	for i := range destinations {
+		destinations[i] = SendDestination{testDestination, big.NewInt(10)}
+	}
+	hashes, err := a.SendMultiple(destinations)
+	require.Nil(t, err)
+	require.Len(t, hashes, 50)
+	assert.Equal(t, 50, node.called("process"))
+
+	// Each block builds on the one before it.
+	blocks, err := a.SendBlocks(destinations[:2])
+	require.Nil(t, err)
+	assert.Equal(t, hashes[0], blocks[1].Previous)
+	assert.Equal(t, "980", blocks[1].Balance.String())
+
+	_, err = a.SendMultiple(append(destinations, SendDestination{testDestination, big.NewInt(600)}))
+	assert.NotNil(t, err)
+
+	node.responses["process"] = `{"error":"Gap previous"}`
+	works := node.called("work_generate")
+	hashes, err = a.SendMultiple(destinations)
+	assert.Equal(t, rpc.Error("Gap previous"), err)
+	assert.Nil(t, hashes)
+	// Work generation stops soon after the first block fails.
+	time.Sleep(50 * time.Millisecond)
+	assert.Less(t, node.called("work_generate")-works, len(destinations))
+}
+
+func TestReceivePendingNotSend(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"block_info":   `{"amount":"1000","subtype":"receive"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	link, _ := rpc.ParseBlockHash(testFrontier)
+	_, err = a.ReceivePending(link)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, node.called("process"))
+}
+
+func TestSkipDust(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	node.responses["accounts_pending"] = `{"blocks":{"` + a.Address() + `":{"` + testFrontier + `":{"amount":"1"},` +
+		`"96D8422D1CB676EF1B62A313865626A7725C3B9BB5B875601A1460ACF30B5322":{"amount":"10000000000000000000000000"}}}}`
+	w.DryRun = true
+	w.SkipDust = true
+	received, err := a.ReceiveAndReturnPendings(new(big.Int))
+	require.Nil(t, err)
+	assert.Len(t, received, 1)
+	require.Nil(t, w.ReceivePendings(big.NewInt(0)))
+	assert.Len(t, w.DryRunBlocks(), 2)
+
+	w.SkipDust = false
+	received, err = a.ReceiveAndReturnPendings(big.NewInt(0))
+	require.Nil(t, err)
+	assert.Len(t, received, 2)
+}
+
+func TestSendAndConfirm(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"block_info":   `{"confirmed":"true"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	hash, err := a.SendAndConfirm(context.Background(), testDestination, big.NewInt(1))
+	require.Nil(t, err)
+	assert.NotNil(t, hash)
+	assert.Equal(t, 1, node.called("block_info"))
+
+	node.responses["block_info"] = `{"confirmed":"false"}`
+	w.ConfirmationPollInterval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	hash, err = a.SendAndConfirm(ctx, testDestination, big.NewInt(1))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.NotNil(t, hash)
+}
+
+func TestIsOpened(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{"account_info": `{"error":"Account not found"}`})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	opened, err := a.IsOpened()
+	require.Nil(t, err)
+	assert.False(t, opened)
+
+	node.responses["account_info"] = `{"frontier":"` + testFrontier + `"}`
+	opened, err = a.IsOpened()
+	require.Nil(t, err)
+	assert.True(t, opened)
+}
+
+func TestReceivePendingsMin(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	var threshold interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var v map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&v)
+		threshold = v["threshold"]
+		rw.Write([]byte(`{"blocks":{"` + a.Address() + `":""}}`))
+	}))
+	defer s.Close()
+	w.RPC.URL = s.URL
+	require.Nil(t, w.ReceivePendingsMin("0.001"))
+	assert.Equal(t, "1000000000000000000000000000", threshold)
+	assert.NotNil(t, w.ReceivePendingsMin("lots"))
+}
+
+func TestSendRetryStale(t *testing.T) {
+	const newFrontier = "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948"
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"process":      `{"error":"Fork"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	_, err = a.Send(testDestination, big.NewInt(400))
+	assert.True(t, errors.Is(err, rpc.ErrFork))
+	assert.Equal(t, 1, node.called("process"))
+
+	w.RetryStaleSend = true
+	_, err = a.Send(testDestination, big.NewInt(400))
+	assert.True(t, errors.Is(err, rpc.ErrFork))
+	assert.Equal(t, 2, node.called("process"))
+
+	var blocks []*rpc.Block
+	w.BeforeBroadcast = func(block *rpc.Block, subtype rpc.BlockSubtype) error {
+		blocks = append(blocks, block)
+		node.mutex.Lock()
+		defer node.mutex.Unlock()
+		if len(blocks) == 1 {
+			node.responses["account_info"] = `{"frontier":"` + newFrontier + `","balance":"600","representative":"` + testDestination + `"}`
+		} else {
+			delete(node.responses, "process")
+		}
+		return nil
+	}
+	_, err = a.Send(testDestination, big.NewInt(400))
+	require.Nil(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, newFrontier, blocks[1].Previous.String())
+	assert.Equal(t, "200", blocks[1].Balance.String())
+
+	// A gap previous isn't retried, even though the frontier changed.
+	node.responses["account_info"] = `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`
+	node.responses["process"] = `{"error":"Gap previous"}`
+	blocks = nil
+	_, err = a.Send(testDestination, big.NewInt(400))
+	assert.True(t, errors.Is(err, rpc.ErrGapPrevious))
+	assert.Len(t, blocks, 1)
+}
+
+func TestReceivePendingsWithRep(t *testing.T) {
+	const rep = "nano_1natrium1o3z5519ifou7xii8crpxpk8y65qmkih8e8bpsjri651oza8imdd"
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"error":"Account not found"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	node.responses["accounts_pending"] = `{"blocks":{"` + a.Address() + `":{"` + testFrontier + `":{"amount":"1"}}}}`
+	w.DryRun = true
+	assert.NotNil(t, a.ReceivePendingsWithRep(nil, "nano_invalid"))
+	require.Nil(t, a.ReceivePendingsWithRep(nil, rep))
+	blocks := w.DryRunBlocks()
+	require.Len(t, blocks, 1)
+	assert.Equal(t, rep, blocks[0].Representative)
+
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	node.responses["account_info"] = `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`
+	node.responses["accounts_pending"] = `{"blocks":{"` + b.Address() + `":{"` + testFrontier + `":{"amount":"1"}}}}`
+	require.Nil(t, b.ReceivePendingsWithRep(nil, rep))
+	blocks = w.DryRunBlocks()
+	require.Len(t, blocks, 1)
+	assert.Equal(t, testDestination, blocks[0].Representative)
+}
+
+func TestSendOnce(t *testing.T) {
+	const sent = "991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948"
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+		"account_history": `{"history":[` +
+			`{"type":"receive","account":"` + testDestination + `","amount":"400","hash":"` + testFrontier + `"},` +
+			`{"type":"send","account":"` + testDestination + `","amount":"500","hash":"` + sent + `"}]}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	hash, err := a.FindSend(testDestination, big.NewInt(400), 10)
+	require.Nil(t, err)
+	assert.Nil(t, hash)
+
+	hash, err = a.SendOnce(testDestination, big.NewInt(500), 10)
+	require.Nil(t, err)
+	assert.Equal(t, sent, hash.String())
+	assert.Zero(t, node.called("process"))
+
+	hash, err = a.SendOnce(testDestination, big.NewInt(400), 10)
+	require.Nil(t, err)
+	assert.NotEqual(t, sent, hash.String())
+	assert.Equal(t, 1, node.called("process"))
+}
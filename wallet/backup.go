@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// backupVersion is the first byte of a backup, followed by the scrypt salt,
+// the XChaCha20-Poly1305 nonce and the encrypted backupData.
+const backupVersion = 1
+
+const backupSaltSize = 16
+
+type backupData struct {
+	Banano    bool            `json:"banano"`
+	Bip39     bool            `json:"bip39"`
+	Seed      []byte          `json:"seed"`
+	NextIndex uint32          `json:"next_index"`
+	Accounts  []backupAccount `json:"accounts"`
+}
+
+type backupAccount struct {
+	Index          uint32 `json:"index"`
+	Representative string `json:"representative,omitempty"`
+}
+
+func backupKey(passphrase, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+}
+
+// Export encrypts the wallet's seed, accounts and their representatives
+// with passphrase, in a form that can be restored with Import. Ledger
+// wallets cannot be exported as their seed never leaves the device, nor can
+// wallets with a custom Derivation or AccountImpl.
+func (w *Wallet) Export(passphrase []byte) (data []byte, err error) {
+	if w.seed == nil {
+		return nil, errors.New("wallet has no seed to export")
+	}
+	if impl, ok := w.impl.(seedImpl); !ok || impl.derive != nil {
+		return nil, errors.New("wallet with a custom derivation cannot be exported")
+	}
+	backup := backupData{Banano: w.isBanano, Bip39: w.isBip39, Seed: w.seed, NextIndex: w.nextIndex}
+	for _, a := range w.GetAccounts() {
+		backup.Accounts = append(backup.Accounts, backupAccount{Index: a.index, Representative: a.representative})
+	}
+	sort.Slice(backup.Accounts, func(i, j int) bool {
+		return backup.Accounts[i].Index < backup.Accounts[j].Index
+	})
+	plaintext, err := json.Marshal(backup)
+	if err != nil {
+		return
+	}
+	data = make([]byte, 1+backupSaltSize+chacha20poly1305.NonceSizeX)
+	data[0] = backupVersion
+	if _, err = rand.Read(data[1:]); err != nil {
+		return nil, err
+	}
+	salt, nonce := data[1:1+backupSaltSize], data[1+backupSaltSize:]
+	key, err := backupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(data, nonce, plaintext, data[:1]), nil
+}
+
+// Import restores a wallet from data created by Export.
+func Import(data, passphrase []byte) (w *Wallet, err error) {
+	if len(data) < 1+backupSaltSize+chacha20poly1305.NonceSizeX || data[0] != backupVersion {
+		return nil, errors.New("unrecognized backup format")
+	}
+	salt, nonce := data[1:1+backupSaltSize], data[1+backupSaltSize:1+backupSaltSize+chacha20poly1305.NonceSizeX]
+	key, err := backupKey(passphrase, salt)
+	if err != nil {
+		return
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return
+	}
+	plaintext, err := aead.Open(nil, nonce, data[1+backupSaltSize+len(nonce):], data[:1])
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted backup")
+	}
+	var backup backupData
+	if err = json.Unmarshal(plaintext, &backup); err != nil {
+		return
+	}
+	w = newWallet(backup.Seed, backup.Banano)
+	w.isBip39 = backup.Bip39
+	for _, b := range backup.Accounts {
+		index := b.Index
+		a, err := w.NewAccount(&index)
+		if err != nil {
+			return nil, err
+		}
+		a.representative = b.Representative
+	}
+	w.nextIndex = backup.NextIndex
+	return
+}
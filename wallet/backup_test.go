@@ -0,0 +1,42 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	w, err := NewBip39BananoWallet("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	index := uint32(7)
+	b, err := w.NewAccount(&index)
+	require.Nil(t, err)
+	require.Nil(t, b.SetRep(DefaultRepresentative))
+
+	data, err := w.Export([]byte("passphrase"))
+	require.Nil(t, err)
+	_, err = Import(data, []byte("wrong"))
+	assert.NotNil(t, err)
+	data[len(data)-1] ^= 1
+	_, err = Import(data, []byte("passphrase"))
+	assert.NotNil(t, err)
+	data[len(data)-1] ^= 1
+
+	w2, err := Import(data, []byte("passphrase"))
+	require.Nil(t, err)
+	assert.Len(t, w2.GetAccounts(), 2)
+	require.NotNil(t, w2.GetAccount(a.Address()))
+	b2 := w2.GetAccount(b.Address())
+	require.NotNil(t, b2)
+	assert.Equal(t, DefaultRepresentative, b2.representative)
+	c, err := w2.NewAccount(nil)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(1), c.Index())
+
+	_, err = Import(data[:10], []byte("passphrase"))
+	assert.NotNil(t, err)
+}
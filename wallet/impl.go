@@ -4,18 +4,74 @@ import (
 	"errors"
 
 	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
 	"github.com/hectorchu/gonano/wallet/ed25519"
 )
 
-type seedImpl struct{}
+// VerifyBlock checks that block is signed by the owner of block.Account.
+// Legacy send, receive and change blocks do not include the account, so it
+// must be filled in by the caller before verifying them.
+func VerifyBlock(block *rpc.Block) (valid bool, err error) {
+	if block.Account == "" {
+		return false, errors.New("block account unknown")
+	}
+	pubkey, err := util.AddressToPubkey(block.Account)
+	if err != nil {
+		return
+	}
+	hash, err := block.Hash()
+	if err != nil {
+		return
+	}
+	return ed25519.Verify(pubkey, hash, block.Signature), nil
+}
 
-func (seedImpl) deriveAccount(a *Account) (err error) {
-	var key []byte
-	if a.w.isBip39 {
-		key, err = deriveBip39Key(a.w.seed, a.index)
-	} else {
-		key, err = deriveKey(a.w.seed, a.index)
+// Derivation derives the ed25519 private key seed of the account at index
+// from a wallet seed.
+type Derivation func(seed []byte, index uint32) (key []byte, err error)
+
+// SeedDerivation is the Derivation of wallets created with NewWallet, the
+// standard Nano derivation of blake2b(seed || index).
+func SeedDerivation(seed []byte, index uint32) (key []byte, err error) {
+	return deriveKey(seed, index)
+}
+
+// Bip39Derivation is the Derivation of wallets created with NewBip39Wallet,
+// which uses the path 44'/165'/index'.
+func Bip39Derivation(seed []byte, index uint32) (key []byte, err error) {
+	return deriveBip39Key(seed, index)
+}
+
+// SetDerivation sets how the keys of accounts are derived from the seed,
+// for matching keys generated by other software. It must be called before
+// any accounts are created.
+func (w *Wallet) SetDerivation(derive Derivation) (err error) {
+	if _, ok := w.impl.(seedImpl); !ok {
+		return errors.New("wallet has no seed")
+	}
+	w.accountsMutex.RLock()
+	defer w.accountsMutex.RUnlock()
+	if len(w.accounts) > 0 {
+		return errors.New("wallet already has accounts")
 	}
+	w.impl = seedImpl{derive: derive}
+	return
+}
+
+type seedImpl struct {
+	// derive overrides the derivation implied by the wallet type.
+	derive Derivation
+}
+
+func (impl seedImpl) deriveAccount(a *Account) (err error) {
+	derive := impl.derive
+	if derive == nil {
+		derive = deriveKey
+		if a.w.isBip39 {
+			derive = deriveBip39Key
+		}
+	}
+	key, err := derive(a.w.seed, a.index)
 	if err != nil {
 		return
 	}
@@ -41,3 +97,32 @@ func (ledgerImpl) deriveAccount(a *Account) (err error) {
 func (ledgerImpl) signBlock(a *Account, block *rpc.Block) (err error) {
 	return errors.New("ledger support not available")
 }
+
+// AccountImpl holds the keys of a wallet's accounts outside of the wallet,
+// such as in an HSM or a remote signing service. Accounts are identified by
+// their derivation index.
+type AccountImpl interface {
+	// DeriveAccount returns the public key of the account at index.
+	DeriveAccount(index uint32) (pubkey []byte, err error)
+	// SignBlock sets the signature of block, which belongs to the account
+	// at index.
+	SignBlock(index uint32, block *rpc.Block) error
+}
+
+type customImpl struct {
+	AccountImpl
+}
+
+func (impl customImpl) deriveAccount(a *Account) (err error) {
+	if a.pubkey, err = impl.DeriveAccount(a.index); err != nil {
+		return
+	}
+	if len(a.pubkey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key length")
+	}
+	return
+}
+
+func (impl customImpl) signBlock(a *Account, block *rpc.Block) (err error) {
+	return impl.SignBlock(a.index, block)
+}
@@ -0,0 +1,130 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBlock(t *testing.T) {
+	source, _ := hex.DecodeString("E89208DD038FBB269987689621D52292AE9C35941A7484756ECCED92A65093BA")
+	signature, _ := hex.DecodeString("9F0C933C8ADE004D808EA1985FA746A7E95BA2A38F867640F53EC8F180BDFE9E" +
+		"2C1268DEAD7C2664F356E37ABA362BC58E46DBA03E523A7B5A19E4B6EB12BB02")
+	genesis := &rpc.Block{
+		Type:           "open",
+		Source:         source,
+		Representative: "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+		Account:        "xrb_3t6k35gi95xu6tergt6p69ck76ogmitsa8mnijtpxm9fkcm736xtoncuohr3",
+		Signature:      signature,
+	}
+	valid, err := VerifyBlock(genesis)
+	require.Nil(t, err)
+	assert.True(t, valid)
+
+	genesis.Representative = "nano_1natrium1o3z5519ifou7xii8crpxpk8y65qmkih8e8bpsjri651oza8imdd"
+	valid, err = VerifyBlock(genesis)
+	require.Nil(t, err)
+	assert.False(t, valid)
+
+	_, err = VerifyBlock(&rpc.Block{Type: "receive", Source: source})
+	assert.NotNil(t, err)
+}
+
+func TestVerifySignedBlock(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	block := &rpc.Block{
+		Type:           "state",
+		Account:        a.Address(),
+		Previous:       make(rpc.BlockHash, 32),
+		Representative: a.Address(),
+		Balance:        &rpc.RawAmount{},
+		Link:           make(rpc.BlockHash, 32),
+	}
+	require.Nil(t, w.impl.signBlock(a, block))
+	valid, err := VerifyBlock(block)
+	require.Nil(t, err)
+	assert.True(t, valid)
+	block.Signature[0] ^= 1
+	valid, err = VerifyBlock(block)
+	require.Nil(t, err)
+	assert.False(t, valid)
+}
+
+func TestSetDerivation(t *testing.T) {
+	seed := make([]byte, 32)
+	w, err := NewWallet(seed)
+	require.Nil(t, err)
+	require.Nil(t, w.SetDerivation(func(seed []byte, index uint32) ([]byte, error) {
+		return SeedDerivation(seed, index+1)
+	}))
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	assert.NotNil(t, w.SetDerivation(Bip39Derivation))
+	_, err = w.Export([]byte("passphrase"))
+	assert.NotNil(t, err)
+
+	w2, err := NewWallet(seed)
+	require.Nil(t, err)
+	index := uint32(1)
+	b, err := w2.NewAccount(&index)
+	require.Nil(t, err)
+	assert.Equal(t, b.Address(), a.Address())
+
+	w3, err := NewLedgerWallet()
+	require.Nil(t, err)
+	assert.NotNil(t, w3.SetDerivation(SeedDerivation))
+}
+
+// testAccountImpl signs with the keys of a seed wallet, as an HSM would.
+type testAccountImpl struct {
+	w *Wallet
+}
+
+func (impl testAccountImpl) DeriveAccount(index uint32) ([]byte, error) {
+	a, err := impl.w.DeriveAccount(index)
+	if err != nil {
+		return nil, err
+	}
+	return a.pubkey, nil
+}
+
+func (impl testAccountImpl) SignBlock(index uint32, block *rpc.Block) error {
+	a, err := impl.w.DeriveAccount(index)
+	if err != nil {
+		return err
+	}
+	return impl.w.impl.signBlock(a, block)
+}
+
+func TestCustomWallet(t *testing.T) {
+	_, err := NewCustomWallet(nil)
+	assert.NotNil(t, err)
+	seedWallet, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	w, err := NewCustomWallet(testAccountImpl{seedWallet})
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	b, err := seedWallet.DeriveAccount(0)
+	require.Nil(t, err)
+	assert.Equal(t, b.Address(), a.Address())
+	block := &rpc.Block{
+		Type:           "state",
+		Account:        a.Address(),
+		Previous:       make(rpc.BlockHash, 32),
+		Representative: a.Address(),
+		Balance:        &rpc.RawAmount{},
+		Link:           make(rpc.BlockHash, 32),
+	}
+	require.Nil(t, w.impl.signBlock(a, block))
+	valid, err := VerifyBlock(block)
+	require.Nil(t, err)
+	assert.True(t, valid)
+	_, err = w.Export([]byte("passphrase"))
+	assert.NotNil(t, err)
+}
@@ -3,17 +3,34 @@ package wallet
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/hectorchu/gonano/wallet/bip32"
 	"github.com/hectorchu/gonano/wallet/ed25519"
 	"github.com/tyler-smith/go-bip39"
 	"golang.org/x/crypto/blake2b"
+	"golang.org/x/text/unicode/norm"
 )
 
+var errSeedLength = errors.New("seed must be 32 bytes")
+
+// ParseSeedHex decodes a hex encoded seed, checking that it is 32 bytes.
+func ParseSeedHex(s string) (seed []byte, err error) {
+	if seed, err = hex.DecodeString(s); err != nil {
+		return nil, errors.New("seed is not valid hex")
+	}
+	if len(seed) != 32 {
+		return nil, errSeedLength
+	}
+	return
+}
+
 func deriveKey(seed []byte, index uint32) (key []byte, err error) {
 	if len(seed) != 32 {
-		err = errors.New("seed must be 32 bytes")
+		err = errSeedLength
 		return
 	}
 	hash, err := blake2b.New256(nil)
@@ -27,12 +44,125 @@ func deriveKey(seed []byte, index uint32) (key []byte, err error) {
 	return hash.Sum(nil), nil
 }
 
+// DeriveKeypair derives the keys of the account at index from a 32 byte
+// seed using the standard Nano derivation. privkey is in the 64 byte form
+// used by ed25519, the first 32 bytes of which are the account's private key.
+func DeriveKeypair(seed []byte, index uint32) (pubkey, privkey []byte, err error) {
+	key, err := deriveKey(seed, index)
+	if err != nil {
+		return
+	}
+	return deriveKeypair(key)
+}
+
 func deriveKeypair(key []byte) (pubkey, privkey []byte, err error) {
 	return ed25519.GenerateKey(bytes.NewReader(key))
 }
 
+// ErrMnemonicChecksum is returned for a BIP39 mnemonic whose words are all
+// valid, but whose checksum doesn't match. This usually means that a word was
+// mistyped as another word of the word list, or that two words were swapped.
+var ErrMnemonicChecksum = errors.New("invalid mnemonic checksum")
+
+// newBip39Seed computes the BIP39 seed of mnemonic, using password as the
+// BIP39 passphrase. The seed is PBKDF2-HMAC-SHA512 of the mnemonic with the
+// salt "mnemonic"+password. Both are hashed as given, without the NFKD
+// normalization BIP39 asks for, so that existing wallets keep deriving the
+// same accounts; callers should pass input through NormalizeMnemonic first.
 func newBip39Seed(mnemonic, password string) (seed []byte, err error) {
-	return bip39.NewSeedWithErrorChecking(mnemonic, password)
+	if err = ValidateMnemonic(mnemonic); err != nil {
+		return
+	}
+	return bip39.NewSeed(mnemonic, password), nil
+}
+
+// NormalizeMnemonic returns mnemonic NFKD normalized, as BIP39 specifies,
+// with its words rejoined with single spaces.
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
+}
+
+// ValidateMnemonic checks that mnemonic is a valid BIP39 mnemonic, with a
+// valid number of words that are all in the word list and a matching
+// checksum. Unknown words are reported with suggestions from
+// SuggestMnemonicWords. The mnemonic is checked after NormalizeMnemonic.
+func ValidateMnemonic(mnemonic string) (err error) {
+	mnemonic = NormalizeMnemonic(mnemonic)
+	for _, word := range strings.Fields(mnemonic) {
+		if _, ok := bip39.GetWordIndex(word); !ok {
+			if suggestions := SuggestMnemonicWords(word); len(suggestions) > 0 {
+				return fmt.Errorf("unknown mnemonic word %q, did you mean %s?",
+					word, strings.Join(suggestions, " or "))
+			}
+			return fmt.Errorf("unknown mnemonic word %q", word)
+		}
+	}
+	switch _, err = bip39.EntropyFromMnemonic(mnemonic); err {
+	case bip39.ErrChecksumIncorrect:
+		return ErrMnemonicChecksum
+	case bip39.ErrInvalidMnemonic:
+		return errors.New("mnemonic must have 12, 15, 18, 21 or 24 words")
+	}
+	return
+}
+
+// SuggestMnemonicWords returns the words of the BIP39 word list that word
+// was most likely a mistyping of. Since the words of the list are unique in
+// their first four letters, a word with the same first four letters as a
+// list word is taken to be that word. Otherwise the list words at the least
+// edit distance from word, at most two edits, are returned in alphabetical order.
+func SuggestMnemonicWords(word string) (suggestions []string) {
+	list := bip39.GetWordList()
+	if len(word) >= 4 {
+		for _, w := range list {
+			if strings.HasPrefix(w, word[:4]) {
+				return []string{w}
+			}
+		}
+	}
+	best := 3
+	for _, w := range list {
+		switch d := editDistance(word, w); {
+		case d < best:
+			best, suggestions = d, []string{w}
+		case d == best:
+			suggestions = append(suggestions, w)
+		}
+	}
+	return
+}
+
+// editDistance returns the edit distance between a and b, counting the
+// insertion, deletion or substitution of a letter and the transposition of
+// two adjacent letters as one edit each.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	min := func(x, y int) int {
+		if x < y {
+			return x
+		}
+		return y
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(min(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
 }
 
 func deriveBip39Key(seed []byte, index uint32) (key []byte, err error) {
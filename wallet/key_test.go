@@ -2,11 +2,13 @@ package wallet
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/hectorchu/gonano/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tyler-smith/go-bip39"
 )
 
 func TestDeriveKey(t *testing.T) {
@@ -28,3 +30,112 @@ func TestBip39(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, "nano_1pu7p5n3ghq1i1p4rhmek41f5add1uh34xpb94nkbxe8g4a6x1p69emk8y1d", address)
 }
+
+func TestParseSeedHex(t *testing.T) {
+	seed, err := ParseSeedHex("0000000000000000000000000000000000000000000000000000000000000001")
+	require.Nil(t, err)
+	assert.Len(t, seed, 32)
+	_, err = ParseSeedHex("0001")
+	assert.Equal(t, errSeedLength, err)
+	_, err = ParseSeedHex("xyz")
+	assert.NotNil(t, err)
+	_, err = NewWallet(make([]byte, 16))
+	assert.Equal(t, errSeedLength, err)
+	_, err = NewBananoWallet(nil)
+	assert.Equal(t, errSeedLength, err)
+}
+
+func TestDeriveKeypair(t *testing.T) {
+	for _, v := range []struct {
+		index            uint32
+		privkey, address string
+	}{
+		{0, "9f0e444c69f77a49bd0be89db92c38fe713e0963165cca12faf5712d7657120f",
+			"nano_3i1aq1cchnmbn9x5rsbap8b15akfh7wj7pwskuzi7ahz8oq6cobd99d4r3b7"},
+		{1, "b73b723bf7bd042b66ad3332718ba98de7312f95ed3d05a130c9204552a7afff",
+			"nano_3rrf6cus8pye6o1kzi5n6wwjof8bjb7ff4xcgesi3njxid6x64pms6onw1f9"},
+	} {
+		pubkey, privkey, err := DeriveKeypair(make([]byte, 32), v.index)
+		require.Nil(t, err)
+		assert.Equal(t, v.privkey, hex.EncodeToString(privkey[:32]))
+		assert.Equal(t, pubkey, privkey[32:])
+		address, err := util.PubkeyToAddress(pubkey)
+		require.Nil(t, err)
+		assert.Equal(t, v.address, address)
+	}
+	_, _, err := DeriveKeypair(make([]byte, 31), 0)
+	assert.NotNil(t, err)
+}
+
+func TestBip39Checksum(t *testing.T) {
+	mnemonic := "edge defense waste choose enrich upon flee junk siren film clown finish " +
+		"luggage leader kid quick brick print evidence swap drill paddle truly occur"
+	_, err := NewBip39Wallet(strings.Replace(mnemonic, "occur", "ocean", 1), "")
+	assert.Equal(t, ErrMnemonicChecksum, err)
+	_, err = NewBip39Wallet(strings.Replace(mnemonic, "occur", "ocur", 1), "")
+	assert.EqualError(t, err, `unknown mnemonic word "ocur", did you mean occur?`)
+	_, err = NewBip39Wallet(strings.Replace(mnemonic, " occur", "", 1), "")
+	assert.EqualError(t, err, "mnemonic must have 12, 15, 18, 21 or 24 words")
+	_, err = NewBip39Wallet(mnemonic, "")
+	assert.Nil(t, err)
+}
+
+func TestSuggestMnemonicWords(t *testing.T) {
+	assert.Equal(t, []string{"abandon"}, SuggestMnemonicWords("abandun"))
+	assert.Equal(t, []string{"zebra"}, SuggestMnemonicWords("zebar"))
+	assert.Contains(t, SuggestMnemonicWords("brik"), "brick")
+	assert.Empty(t, SuggestMnemonicWords("qqqqqqqqqq"))
+}
+
+func TestBip39MnemonicLengths(t *testing.T) {
+	// Vectors for all-zero entropy with the passphrase "TREZOR".
+	for _, tt := range []struct {
+		words    int
+		last     string
+		expected string
+	}{
+		{12, "about", "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"},
+		{15, "address", "fa08713f46bf5cb48728ceb70e3aae1bc53c5cb7b4e29c5610261d1cbb7be3bed4d805256fec515754d2be35974fc5da678168e9d9bb0cb70948026923b0def3"},
+		{18, "agent", "035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa"},
+		{21, "admit", "e7dadc189d2e8d07ac278d9ec98a1d2d327e4a6b7df494c00cbf2cbf2d3543dac7000fc72d4ada8d9997dc8db388ff22c6d79f604a7455f2df5534a28eee04c6"},
+		{24, "art", "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8"},
+	} {
+		mnemonic := strings.Repeat("abandon ", tt.words-1) + tt.last
+		seed, err := newBip39Seed(mnemonic, "TREZOR")
+		require.Nil(t, err, tt.words)
+		assert.Equal(t, tt.expected, hex.EncodeToString(seed), tt.words)
+	}
+}
+
+func TestBip39Passphrase(t *testing.T) {
+	mnemonic := "edge defense waste choose enrich upon flee junk siren film clown finish " +
+		"luggage leader kid quick brick print evidence swap drill paddle truly occur"
+	seed, err := newBip39Seed(mnemonic, "some password")
+	require.Nil(t, err)
+	assert.Equal(t, "0dc285fde768f7ff29b66ce7252d56ed92fe003b605907f7a4f683c3dc8586d3"+
+		"4a914d3c71fc099bb38ee4a59e5b081a3497b7a323e90cc68f67b5837690310c", hex.EncodeToString(seed))
+
+	// The mnemonic is hashed as given, as it was before validation was
+	// added, so stray whitespace changes the seed unless normalized first.
+	spaced := "  " + strings.ReplaceAll(mnemonic, " ", " \t ") + "\n"
+	require.Nil(t, ValidateMnemonic(spaced))
+	seed2, err := newBip39Seed(spaced, "some password")
+	require.Nil(t, err)
+	assert.Equal(t, bip39.NewSeed(spaced, "some password"), seed2)
+	assert.NotEqual(t, seed, seed2)
+	assert.Equal(t, mnemonic, NormalizeMnemonic(spaced))
+	seed2, err = newBip39Seed(NormalizeMnemonic(spaced), "some password")
+	require.Nil(t, err)
+	assert.Equal(t, seed, seed2)
+
+	seed2, err = newBip39Seed(mnemonic, "")
+	require.Nil(t, err)
+	assert.NotEqual(t, seed, seed2)
+
+	// Neither is the passphrase normalized.
+	seed, err = newBip39Seed(mnemonic, "caf\u00e9")
+	require.Nil(t, err)
+	seed2, err = newBip39Seed(mnemonic, "cafe\u0301")
+	require.Nil(t, err)
+	assert.NotEqual(t, seed, seed2)
+}
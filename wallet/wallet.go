@@ -1,25 +1,86 @@
 package wallet
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"math/big"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hectorchu/gonano/rpc"
 	"github.com/hectorchu/gonano/util"
+	"github.com/hectorchu/gonano/websocket"
 )
 
 // Wallet represents a wallet.
 type Wallet struct {
-	isBanano              bool
-	seed                  []byte
-	isBip39               bool
-	nextIndex             uint32
-	accounts              map[string]*Account
-	accountsMutex         sync.RWMutex
-	RPC, RPCWork          rpc.Client
-	WorkDifficulty        string
-	ReceiveWorkDifficulty string
-	impl                  interface {
+	isBanano      bool
+	seed          []byte
+	isBip39       bool
+	nextIndex     uint32
+	accounts      map[string]*Account
+	accountsMutex sync.RWMutex
+	RPC, RPCWork  rpc.Client
+	// WorkServers are additional work servers to try, in order, should
+	// RPCWork fail. Work is generated on the CPU if all of them fail.
+	WorkServers []rpc.Client
+	// UseWorkPeers asks the work servers to use their configured work peers.
+	UseWorkPeers bool
+	// WorkProviders, if set, generate work instead of RPCWork, WorkServers
+	// and the CPU. The first of them to succeed is used.
+	WorkProviders []WorkProvider
+	// OnWorkGenerated, if set, is called with the data and work whenever
+	// work is generated, and the URL of the work server that generated it,
+	// or WorkSourceCPU. Work servers that failed are not reported.
+	OnWorkGenerated func(data, work []byte, source string)
+	// CPUWorkConcurrency limits how many blocks can have their work
+	// generated on the CPU at once, across all accounts, as each generation
+	// already uses every CPU. Zero means no limit.
+	CPUWorkConcurrency    int
+	cpuWorkSem            chan struct{}
+	cpuWorkSemOnce        sync.Once
+	WorkDifficulty        rpc.HexData
+	ReceiveWorkDifficulty rpc.HexData
+	// UseNetworkReceiveDifficulty raises the difficulty of receive work to
+	// the network's current receive difficulty, as reported by the node's
+	// ActiveDifficulty, when higher than ReceiveWorkDifficulty. Work that
+	// doesn't meet the difficulty is regenerated before publishing.
+	UseNetworkReceiveDifficulty bool
+	// ValidateWork checks the work of every block against its difficulty
+	// before publishing it, so that bad work from a work server is reported
+	// without a round trip to the node.
+	ValidateWork bool
+	// DryRun causes blocks to be signed and given work as usual, but not
+	// published. Block hashes are computed locally instead, and the blocks
+	// are kept for inspection until retrieved with DryRunBlocks.
+	DryRun       bool
+	dryRunBlocks []*rpc.Block
+	dryRunMutex  sync.Mutex
+	// RetryStaleSend makes Account.Send build a rejected send again, once,
+	// when the node reports a fork or old block because the account's
+	// frontier changed after it was looked up, such as by another process
+	// sending from the same account. The amount is then sent on top of the
+	// other blocks, so it must not be set if they could be the same send.
+	RetryStaleSend bool
+	// SkipDust raises the threshold for receiving pending amounts to at
+	// least 0.000001 Nano, or Banano, so that spam dust is never pocketed.
+	SkipDust bool
+	// BeforeBroadcast, if set, is called with every block the wallet is
+	// about to publish, once it is signed and has work. Returning an error
+	// aborts publishing the block, and the error is returned to the caller.
+	BeforeBroadcast func(block *rpc.Block, subtype rpc.BlockSubtype) error
+	// ConfirmationStrategy is how WaitForConfirmation detects confirmations.
+	ConfirmationStrategy ConfirmationStrategy
+	// ConfirmationPollInterval is how often WaitForConfirmation checks
+	// whether a block is confirmed, one second if zero or negative.
+	ConfirmationPollInterval time.Duration
+	// WebsocketURL is the node websocket used by ConfirmByWebsocket.
+	WebsocketURL string
+	impl         interface {
 		deriveAccount(*Account) error
 		signBlock(*Account, *rpc.Block) error
 	}
@@ -27,17 +88,26 @@ type Wallet struct {
 
 // NewWallet creates a new wallet.
 func NewWallet(seed []byte) (w *Wallet, err error) {
+	if len(seed) != 32 {
+		return nil, errSeedLength
+	}
 	w = newWallet(seed, false)
 	return
 }
 
 // NewBananoWallet creates a new Banano wallet.
 func NewBananoWallet(seed []byte) (w *Wallet, err error) {
+	if len(seed) != 32 {
+		return nil, errSeedLength
+	}
 	w = newWallet(seed, true)
 	return
 }
 
-// NewBip39Wallet creates a new BIP39 wallet.
+// NewBip39Wallet creates a new BIP39 wallet from a mnemonic of 12, 15, 18,
+// 21 or 24 English words. password is the optional BIP39 passphrase, which
+// must be the same as given to other wallets, such as hardware wallets, to
+// derive the same accounts. Accounts are derived at the path 44'/165'/index'.
 func NewBip39Wallet(mnemonic, password string) (w *Wallet, err error) {
 	seed, err := newBip39Seed(mnemonic, password)
 	if err != nil {
@@ -48,7 +118,7 @@ func NewBip39Wallet(mnemonic, password string) (w *Wallet, err error) {
 	return
 }
 
-// NewBip39BananoWallet creates a new BIP39 Banano wallet.
+// NewBip39BananoWallet creates a new BIP39 Banano wallet, as NewBip39Wallet.
 func NewBip39BananoWallet(mnemonic, password string) (w *Wallet, err error) {
 	seed, err := newBip39Seed(mnemonic, password)
 	if err != nil {
@@ -66,60 +136,268 @@ func NewLedgerWallet() (w *Wallet, err error) {
 	return
 }
 
+// NewCustomWallet creates a new wallet whose keys are held by impl. Such a
+// wallet has no seed, so it can't be backed up.
+func NewCustomWallet(impl AccountImpl) (w *Wallet, err error) {
+	if impl == nil {
+		return nil, errors.New("impl must not be nil")
+	}
+	w = newWallet(nil, false)
+	w.impl = customImpl{impl}
+	return
+}
+
+// DefaultRPCURL is the RPC endpoint that new Nano wallets connect to. It is
+// empty by default, so either it or Wallet.RPC.URL must be set before use.
+var DefaultRPCURL string
+
+// DefaultBananoRPCURL is the RPC endpoint that new Banano wallets connect to.
+var DefaultBananoRPCURL = "https://api-beta.banano.cc"
+
+// DefaultRPCWorkURL is the work server that new wallets use. Setting it, or
+// Wallet.RPCWork.URL, to the empty string generates work on the CPU only.
+var DefaultRPCWorkURL = "http://[::1]:7076"
+
+// Work difficulties that new wallets use for their network.
+var (
+	DefaultSendDifficulty    = rpc.HexDataFromUint64(0xfffffff800000000)
+	DefaultReceiveDifficulty = rpc.HexDataFromUint64(0xfffffe0000000000)
+	// BananoDifficulty is used for all Banano blocks.
+	BananoDifficulty = rpc.HexDataFromUint64(0xfffffe0000000000)
+)
+
+// DefaultRepresentative is the representative given to accounts opened by
+// the wallet when no representative has been set with SetRep.
+const DefaultRepresentative = "nano_3gonano8jnse4zm65jaiki9tk8ry4jtgc1smarinukho6fmbc45k3icsh6en"
+
 func newWallet(seed []byte, isBanano bool) *Wallet {
 	w := &Wallet{
 		isBanano:              isBanano,
 		seed:                  seed,
 		accounts:              make(map[string]*Account),
-		RPC:                   rpc.Client{URL: "https://mynano.ninja/api/node"},
-		RPCWork:               rpc.Client{URL: "http://[::1]:7076"},
+		RPC:                   rpc.Client{URL: DefaultRPCURL},
+		RPCWork:               rpc.Client{URL: DefaultRPCWorkURL},
 		impl:                  seedImpl{},
-		WorkDifficulty:        "fffffff800000000",
-		ReceiveWorkDifficulty: "fffffe0000000000",
+		WorkDifficulty:        DefaultSendDifficulty,
+		ReceiveWorkDifficulty: DefaultReceiveDifficulty,
 	}
 	if isBanano {
-		w.RPC = rpc.Client{URL: "https://api-beta.banano.cc"}
+		w.RPC = rpc.Client{URL: DefaultBananoRPCURL}
+		w.WorkDifficulty = BananoDifficulty
+		w.ReceiveWorkDifficulty = BananoDifficulty
 	}
 	return w
 }
 
-// ScanForAccounts scans for accounts.
+// FormatAmount formats a raw amount in the units of the wallet's network.
+func (w *Wallet) FormatAmount(raw *big.Int) string {
+	if w.isBanano {
+		return util.BananoAmount{Raw: raw}.String()
+	}
+	return util.NanoAmount{Raw: raw}.String()
+}
+
+// ParseAmount parses an amount expressed in the units of the wallet's
+// network into a raw amount.
+func (w *Wallet) ParseAmount(s string) (raw *big.Int, err error) {
+	if w.isBanano {
+		n, err := util.BananoAmountFromString(s)
+		return n.Raw, err
+	}
+	n, err := util.NanoAmountFromString(s)
+	return n.Raw, err
+}
+
+// ScanForAccounts scans for accounts. Accounts are only added to the wallet
+// once the node has been queried successfully, and an account the node
+// doesn't report on is kept rather than assumed to be unused.
 func (w *Wallet) ScanForAccounts() (err error) {
-	accounts := make([]string, 10)
-	for i := range accounts {
-		a, err := w.NewAccount(nil)
+	for {
+		accounts := make([]*Account, 10)
+		for i := range accounts {
+			if accounts[i], err = w.DeriveAccount(w.nextIndex + uint32(i)); err != nil {
+				return
+			}
+		}
+		active, err := w.probeAccounts(accounts)
 		if err != nil {
 			return err
 		}
-		accounts[i] = a.Address()
+		last := -1
+		for i := range active {
+			if active[i] {
+				last = i
+			}
+		}
+		w.addAccounts(accounts[:last+1])
+		if last < 5 {
+			return nil
+		}
+	}
+}
+
+// addAccounts adds accounts to the wallet, skipping those already present,
+// and advances the next index past them.
+func (w *Wallet) addAccounts(accounts []*Account) {
+	w.accountsMutex.Lock()
+	defer w.accountsMutex.Unlock()
+	for _, a := range accounts {
+		if _, ok := w.accounts[a.address]; !ok {
+			w.accounts[a.address] = a
+		}
+		if a.index >= w.nextIndex {
+			w.nextIndex = a.index + 1
+		}
+	}
+}
+
+// NextUnusedAccount returns the lowest-index account that has no blocks and
+// no pending funds, adding it to the wallet. Unlike NewAccount, calling it
+// repeatedly returns the same account until that account is used.
+func (w *Wallet) NextUnusedAccount() (a *Account, err error) {
+	for index := uint32(0); ; index += 10 {
+		accounts := make([]*Account, 10)
+		for i := range accounts {
+			if accounts[i], err = w.DeriveAccount(index + uint32(i)); err != nil {
+				return
+			}
+		}
+		active, err := w.probeAccounts(accounts)
+		if err != nil {
+			return nil, err
+		}
+		for i, a := range accounts {
+			if !active[i] {
+				w.addAccounts([]*Account{a})
+				return w.GetAccount(a.address), nil
+			}
+		}
 	}
-	balances, err := w.RPC.AccountsBalances(accounts)
+}
+
+// scanConcurrency is the number of batches probed at once by ScanForActiveAccounts.
+const scanConcurrency = 4
+
+// ScanForActiveAccounts discovers the accounts of the wallet that have blocks
+// or pending funds, starting at the next account index. Accounts are probed
+// in batches of gapLimit, several batches at a time, and the scan stops once
+// gapLimit consecutive accounts are found to be unused. The active accounts
+// found are added to the wallet and returned in index order.
+func (w *Wallet) ScanForActiveAccounts(gapLimit int) (accounts []*Account, err error) {
+	if gapLimit <= 0 {
+		return nil, errors.New("gap limit must be positive")
+	}
+	index, gap := w.nextIndex, 0
+	for gap < gapLimit {
+		batches := make([][]*Account, scanConcurrency)
+		for i := range batches {
+			for j := 0; j < gapLimit; j++ {
+				a, err := w.DeriveAccount(index)
+				if err != nil {
+					return nil, err
+				}
+				batches[i] = append(batches[i], a)
+				index++
+			}
+		}
+		var (
+			wg     sync.WaitGroup
+			active = make([][]bool, len(batches))
+			errs   = make([]error, len(batches))
+		)
+		for i := range batches {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				active[i], errs[i] = w.probeAccounts(batches[i])
+			}(i)
+		}
+		wg.Wait()
+		for i := range batches {
+			if errs[i] != nil {
+				return nil, errs[i]
+			}
+			for j, a := range batches[i] {
+				if gap >= gapLimit {
+					break
+				}
+				if !active[i][j] {
+					gap++
+					continue
+				}
+				gap = 0
+				accounts = append(accounts, a)
+			}
+		}
+	}
+	w.addAccounts(accounts)
+	for i, a := range accounts {
+		accounts[i] = w.GetAccount(a.address)
+	}
+	return
+}
+
+// probeAccounts reports which of accounts have blocks or pending funds.
+// Accounts missing from the node's response are reported as active, so that
+// a partial response never causes a funded account to be dropped.
+func (w *Wallet) probeAccounts(accounts []*Account) (active []bool, err error) {
+	addresses := make([]string, len(accounts))
+	for i, a := range accounts {
+		addresses[i] = a.address
+	}
+	balances, err := w.RPC.AccountsBalances(addresses)
 	if err != nil {
 		return
 	}
-	frontiers, err := w.RPC.AccountsFrontiers(accounts)
+	frontiers, err := w.RPC.AccountsFrontiers(addresses)
 	if err != nil {
 		return
 	}
-	i := len(accounts) - 1
-	for ; i >= 0; i-- {
-		if balances[accounts[i]].Pending.Sign() > 0 {
-			break
-		}
-		if frontiers[accounts[i]] != nil {
-			break
+	if len(balances) == 0 {
+		return nil, errors.New("no balances returned")
+	}
+	active = make([]bool, len(accounts))
+	for i, address := range addresses {
+		balance := balances[address]
+		active[i] = balance == nil || frontiers[address] != nil || balance.Pending.Sign() > 0
+	}
+	return
+}
+
+// deriveConcurrency is the number of accounts derived at once by LoadAccounts.
+const deriveConcurrency = 4
+
+// LoadAccounts adds the accounts at indices to the wallet, such as ones
+// persisted from an earlier session, and returns them in the same order. The
+// next account index is moved past the highest of them. No accounts are added
+// if any of them fail to derive.
+func (w *Wallet) LoadAccounts(indices []uint32) (accounts []*Account, err error) {
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, deriveConcurrency)
+		errs = make([]error, len(indices))
+	)
+	accounts = make([]*Account, len(indices))
+	for i, index := range indices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, index uint32) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			accounts[i], errs[i] = w.DeriveAccount(index)
+		}(i, index)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
 		}
-		func() {
-			w.accountsMutex.Lock()
-			defer w.accountsMutex.Unlock()
-			w.nextIndex = w.accounts[accounts[i]].index
-			delete(w.accounts, accounts[i])
-		}()
 	}
-	if i < 5 {
-		return
+	w.addAccounts(accounts)
+	for i, a := range accounts {
+		accounts[i] = w.GetAccount(a.address)
 	}
-	return w.ScanForAccounts()
+	return
 }
 
 // NewAccount creates a new account.
@@ -128,15 +406,7 @@ func (w *Wallet) NewAccount(index *uint32) (a *Account, err error) {
 	if index != nil {
 		index2 = *index
 	}
-	a = &Account{w: w, index: index2}
-	if err = w.impl.deriveAccount(a); err != nil {
-		return
-	}
-	pubkeyToAddress := util.PubkeyToAddress
-	if w.isBanano {
-		pubkeyToAddress = util.PubkeyToBananoAddress
-	}
-	if a.address, err = pubkeyToAddress(a.pubkey); err != nil {
+	if a, err = w.DeriveAccount(index2); err != nil {
 		return
 	}
 	if index == nil {
@@ -159,6 +429,43 @@ func (w *Wallet) NewAccount(index *uint32) (a *Account, err error) {
 	return
 }
 
+// DeriveAccount derives the account at index without adding it to the
+// wallet, so that its address can be inspected.
+func (w *Wallet) DeriveAccount(index uint32) (a *Account, err error) {
+	a = &Account{w: w, index: index}
+	if err = w.impl.deriveAccount(a); err != nil {
+		return
+	}
+	pubkeyToAddress := util.PubkeyToAddress
+	if w.isBanano {
+		pubkeyToAddress = util.PubkeyToBananoAddress
+	}
+	a.address, err = pubkeyToAddress(a.pubkey)
+	return
+}
+
+// OwnsAddress reports whether address is one of the wallet's accounts, or
+// is derived from the wallet at an index below searchDepth. Derived accounts
+// aren't added to the wallet. Addresses are compared by public key, so the
+// prefix is ignored.
+func (w *Wallet) OwnsAddress(address string, searchDepth uint32) bool {
+	pubkey, err := util.AddressToPubkey(address)
+	if err != nil {
+		return false
+	}
+	for _, a := range w.GetAccounts() {
+		if bytes.Equal(a.pubkey, pubkey) {
+			return true
+		}
+	}
+	for i := uint32(0); i < searchDepth; i++ {
+		if a, err := w.DeriveAccount(i); err == nil && bytes.Equal(a.pubkey, pubkey) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAccount gets the account with address or nil if not found.
 func (w *Wallet) GetAccount(address string) *Account {
 	w.accountsMutex.RLock()
@@ -178,28 +485,408 @@ func (w *Wallet) GetAccounts() (accounts []*Account) {
 	return
 }
 
+// RemoveAccount removes the account with address from the wallet. It does
+// nothing if the account is not in the wallet.
+func (w *Wallet) RemoveAccount(address string) {
+	w.accountsMutex.Lock()
+	defer w.accountsMutex.Unlock()
+	delete(w.accounts, address)
+}
+
+// Receivable returns the pending blocks of every account in the wallet
+// without pocketing them.
+func (w *Wallet) Receivable(threshold *big.Int) (pendings map[string]rpc.HashToPendingMap, err error) {
+	return w.RPC.AccountsPending(w.addresses(), -1, &rpc.RawAmount{Int: *w.receiveThreshold(threshold)})
+}
+
+// TotalBalance gets the confirmed balance and pending amount of the wallet,
+// summed across all of its accounts with a single request.
+func (w *Wallet) TotalBalance() (confirmed, pending *big.Int, err error) {
+	confirmed, pending = new(big.Int), new(big.Int)
+	addresses := w.addresses()
+	if len(addresses) == 0 {
+		return
+	}
+	balances, err := w.RPC.AccountsBalancesWithOptions(addresses, rpc.AccountBalanceOptions{IncludeOnlyConfirmed: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, b := range balances {
+		if b.Balance != nil {
+			confirmed.Add(confirmed, &b.Balance.Int)
+		}
+		if b.Pending != nil {
+			pending.Add(pending, &b.Pending.Int)
+		}
+	}
+	return
+}
+
+// addresses returns the addresses of the wallet's accounts.
+func (w *Wallet) addresses() (accounts []string) {
+	w.accountsMutex.RLock()
+	defer w.accountsMutex.RUnlock()
+	accounts = make([]string, 0, len(w.accounts))
+	for address := range w.accounts {
+		accounts = append(accounts, address)
+	}
+	return
+}
+
+// receiveThreshold returns the threshold to receive pending amounts at,
+// taking SkipDust into account.
+func (w *Wallet) receiveThreshold(threshold *big.Int) *big.Int {
+	if threshold == nil {
+		threshold = new(big.Int)
+	}
+	if w.SkipDust {
+		if dust, _ := w.ParseAmount("0.000001"); threshold.Cmp(dust) < 0 {
+			return dust
+		}
+	}
+	return threshold
+}
+
 // ReceivePendings pockets all pending amounts.
 func (w *Wallet) ReceivePendings(threshold *big.Int) (err error) {
+	return w.ReceivePendingsContext(context.Background(), threshold)
+}
 
-	var accounts []string
-	accountsMapCopy := make(map[string]*Account)
-	func() {
-		w.accountsMutex.RLock()
-		defer w.accountsMutex.RUnlock()
-		accounts = make([]string, 0, len(w.accounts))
-		for address, account := range w.accounts {
-			accounts = append(accounts, address)
-			accountsMapCopy[address] = account
-		}
-	}()
-	pendings, err := w.RPC.AccountsPending(accounts, -1, &rpc.RawAmount{Int: *threshold})
+// ReceivePendingsMin is like ReceivePendings, with the threshold given in
+// the units of the wallet's network, e.g. "0.001" for 0.001 Nano, rather
+// than in raw.
+func (w *Wallet) ReceivePendingsMin(min string) (err error) {
+	threshold, err := w.ParseAmount(min)
 	if err != nil {
 		return
 	}
+	return w.ReceivePendings(threshold)
+}
+
+// receiveConcurrency is the number of accounts that ReceivePendings
+// pockets pending amounts for at once.
+const receiveConcurrency = 4
+
+// ReceivePendingsContext is like ReceivePendings, but stops once ctx is
+// done. ctx is checked before each account and each receive.
+//
+// Accounts are received for concurrently, though the blocks of each account
+// are still created one at a time. An account that fails doesn't stop the
+// others; the errors are returned as AccountErrors. Accounts left out when
+// ctx is done are in them with ctx.Err(), which errors.Is finds.
+func (w *Wallet) ReceivePendingsContext(ctx context.Context, threshold *big.Int) (err error) {
+	threshold = w.receiveThreshold(threshold)
+	pendings, err := w.Receivable(threshold)
+	if err != nil {
+		return
+	}
+	for account := range pendings {
+		pendings[account] = filterPendings(pendings[account], threshold)
+	}
+	return w.receiveAccountPendings(ctx, pendings)
+}
+
+// ReceivePendingsLimit is like ReceivePendingsContext, but pockets at most
+// limit pending amounts across all accounts. The amounts are picked from
+// each account in turn, largest first, so that an account with many pending
+// amounts doesn't starve the others.
+func (w *Wallet) ReceivePendingsLimit(ctx context.Context, threshold *big.Int, limit int) (err error) {
+	if limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+	threshold = w.receiveThreshold(threshold)
+	pendings, err := w.RPC.AccountsPendingWithOptions(w.addresses(), rpc.AccountsPendingOptions{
+		Count:     int64(limit),
+		Threshold: &rpc.RawAmount{Int: *threshold},
+		Sorting:   true,
+	})
+	if err != nil {
+		return
+	}
+	accounts := make([]string, 0, len(pendings))
+	sorted := make(map[string][]string, len(pendings))
+	for account, p := range pendings {
+		accounts = append(accounts, account)
+		sorted[account] = filterPendings(p, threshold).Sorted()
+	}
+	sort.Strings(accounts)
+	picked := make(map[string]rpc.HashToPendingMap)
+	for i, n := 0, 0; n < limit; i++ {
+		more := false
+		for _, account := range accounts {
+			if i >= len(sorted[account]) || n == limit {
+				continue
+			}
+			if picked[account] == nil {
+				picked[account] = make(rpc.HashToPendingMap)
+			}
+			hash := sorted[account][i]
+			picked[account][hash] = pendings[account][hash]
+			more = true
+			n++
+		}
+		if !more {
+			break
+		}
+	}
+	return w.receiveAccountPendings(ctx, picked)
+}
+
+// receiveAccountPendings pockets pendings, which is keyed by account, for
+// up to receiveConcurrency accounts at once.
+func (w *Wallet) receiveAccountPendings(ctx context.Context, pendings map[string]rpc.HashToPendingMap) (err error) {
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		sem   = make(chan struct{}, receiveConcurrency)
+		errs  = make(AccountErrors)
+	)
 	for account, pendings := range pendings {
-		if err = accountsMapCopy[account].receivePendings(pendings); err != nil {
+		a := w.GetAccount(account)
+		if a == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			mutex.Lock()
+			errs[a.address] = err
+			mutex.Unlock()
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(a *Account, pendings rpc.HashToPendingMap) {
+			defer func() { <-sem; wg.Done() }()
+			if err := a.receivePendings(ctx, pendings); err != nil {
+				mutex.Lock()
+				errs[a.address] = err
+				mutex.Unlock()
+			}
+		}(a, pendings)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		err = errs
+	}
+	return
+}
+
+// DryRunBlocks returns the blocks created in dry-run mode since the last call.
+func (w *Wallet) DryRunBlocks() (blocks []*rpc.Block) {
+	w.dryRunMutex.Lock()
+	defer w.dryRunMutex.Unlock()
+	blocks, w.dryRunBlocks = w.dryRunBlocks, nil
+	return
+}
+
+func (w *Wallet) process(block *rpc.Block, subtype rpc.BlockSubtype) (hash rpc.BlockHash, err error) {
+	if w.ValidateWork {
+		if err = w.validateWork(block, subtype); err != nil {
+			return
+		}
+	}
+	if w.BeforeBroadcast != nil {
+		if err = w.BeforeBroadcast(block, subtype); err != nil {
+			return
+		}
+	}
+	local, err := block.Hash()
+	if err != nil {
+		return
+	}
+	if !w.DryRun {
+		if hash, err = w.RPC.Process(block, subtype); errors.Is(err, rpc.ErrFork) {
+			err = w.forkError(block)
+		} else if err == nil && !bytes.Equal(hash, local) {
+			// The node hashed a different block to the one that was
+			// signed, so the block wasn't serialized faithfully.
+			err = fmt.Errorf("node returned hash %s for block with hash %s", hash, local)
+		}
+		return
+	}
+	hash = local
+	w.dryRunMutex.Lock()
+	defer w.dryRunMutex.Unlock()
+	w.dryRunBlocks = append(w.dryRunBlocks, block)
+	return
+}
+
+// ConfirmationStrategy selects how WaitForConfirmation detects that a block
+// is confirmed.
+type ConfirmationStrategy int
+
+const (
+	// ConfirmByPolling polls block_info until the block is confirmed.
+	ConfirmByPolling ConfirmationStrategy = iota
+	// ConfirmByRequest asks the node to start an election for the block
+	// with block_confirm, then polls as ConfirmByPolling.
+	ConfirmByRequest
+	// ConfirmByWebsocket waits for the confirmation to be reported on the
+	// node websocket at Wallet.WebsocketURL.
+	ConfirmByWebsocket
+)
+
+// WaitForConfirmation waits until the block with hash is confirmed or ctx
+// is done, using the wallet's ConfirmationStrategy. It returns immediately
+// in DryRun mode, since nothing has been published.
+func (w *Wallet) WaitForConfirmation(ctx context.Context, hash rpc.BlockHash) (err error) {
+	if w.DryRun {
+		return
+	}
+	switch w.ConfirmationStrategy {
+	case ConfirmByPolling:
+	case ConfirmByRequest:
+		if _, err = w.RPC.BlockConfirm(hash); err != nil {
+			return
+		}
+	case ConfirmByWebsocket:
+		return w.waitForConfirmationWebsocket(ctx, hash)
+	default:
+		return errors.New("unknown confirmation strategy")
+	}
+	interval := w.ConfirmationPollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		info, err := w.RPC.BlockInfo(hash)
+		if err != nil {
+			return err
+		}
+		if info.Confirmed {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *Wallet) waitForConfirmationWebsocket(ctx context.Context, hash rpc.BlockHash) (err error) {
+	ws := &websocket.Client{URL: w.WebsocketURL, Ctx: ctx}
+	if err = ws.Connect(); err != nil {
+		return
+	}
+	defer ws.Close()
+	// The block may have been confirmed before we subscribed.
+	info, err := w.RPC.BlockInfo(hash)
+	if err != nil || info.Confirmed {
+		return
+	}
+	for {
+		select {
+		case m := <-ws.Messages:
+			switch m := m.(type) {
+			case error:
+				return m
+			case *websocket.Confirmation:
+				if bytes.Equal(m.Hash, hash) {
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// AccountErrors maps the addresses of accounts to the errors encountered
+// for them by an operation on many accounts.
+type AccountErrors map[string]error
+
+func (e AccountErrors) Error() string {
+	addresses := make([]string, 0, len(e))
+	for address := range e {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	msgs := make([]string, len(addresses))
+	for i, address := range addresses {
+		msgs[i] = address + ": " + e[address].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether the error of any account is target.
+func (e AccountErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeRepAll changes the representative of every opened account in the
+// wallet, returning the hashes of the change blocks by account address.
+// Accounts that fail don't stop the others from being changed; their errors
+// are returned as AccountErrors.
+func (w *Wallet) ChangeRepAll(representative string) (hashes map[string]rpc.BlockHash, err error) {
+	if _, err = util.AddressToPubkey(representative); err != nil {
+		return
+	}
+	hashes = make(map[string]rpc.BlockHash)
+	errs := make(AccountErrors)
+	for _, a := range w.GetAccounts() {
+		hash, err := a.ChangeRep(representative)
+		switch {
+		case errors.Is(err, rpc.ErrAccountNotFound):
+		case err != nil:
+			errs[a.address] = err
+		default:
+			hashes[a.address] = hash
+		}
+	}
+	if len(errs) > 0 {
+		err = errs
+	}
+	return
+}
+
+// SweepTo pockets all pending amounts and then sends the entire balance of
+// every account in the wallet to destination. Accounts with no balance are
+// skipped, as is destination itself should it belong to the wallet. An
+// account that fails to receive or send is skipped, and the errors of all
+// such accounts are returned together as AccountErrors, along with the
+// hashes of the sends that succeeded.
+func (w *Wallet) SweepTo(destination string) (hashes []rpc.BlockHash, err error) {
+	pubkey, err := util.AddressToPubkey(destination)
+	if err != nil {
+		return
+	}
+	errs := make(AccountErrors)
+	if err = w.ReceivePendings(new(big.Int)); err != nil {
+		var receiveErrs AccountErrors
+		if !errors.As(err, &receiveErrs) {
 			return
 		}
+		for address, err := range receiveErrs {
+			errs[address] = err
+		}
+		err = nil
+	}
+	accounts := w.GetAccounts()
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].index < accounts[j].index
+	})
+	for _, a := range accounts {
+		if _, ok := errs[a.address]; ok || bytes.Equal(a.pubkey, pubkey) {
+			continue
+		}
+		hash, err := a.SendAll(destination)
+		if err != nil {
+			errs[a.address] = err
+			continue
+		}
+		if hash != nil {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(errs) > 0 {
+		err = errs
 	}
 	return
 }
@@ -0,0 +1,541 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepTo(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"accounts_pending": `{"blocks":""}`,
+		"account_info":     `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	w.DryRun = true
+	for i := 0; i < 2; i++ {
+		_, err := w.NewAccount(nil)
+		require.Nil(t, err)
+	}
+	hashes, err := w.SweepTo(testDestination)
+	require.Nil(t, err)
+	assert.Len(t, hashes, 2)
+	blocks := w.DryRunBlocks()
+	require.Len(t, blocks, 2)
+	for _, b := range blocks {
+		assert.Equal(t, 0, b.Balance.Sign())
+	}
+}
+
+func TestSweepToAccountErrors(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	var accounts []*Account
+	for i := 0; i < 3; i++ {
+		a, err := w.NewAccount(nil)
+		require.Nil(t, err)
+		accounts = append(accounts, a)
+	}
+	node.responses["accounts_pending"] = `{"blocks":{` +
+		`"` + accounts[0].Address() + `":{"` + testFrontier + `":{"amount":"1"}},` +
+		`"` + accounts[1].Address() + `":{"` + testFrontier + `":{"amount":"1"}}}}`
+	failed := errors.New("receive failed")
+	w.BeforeBroadcast = func(block *rpc.Block, subtype rpc.BlockSubtype) error {
+		if subtype == rpc.SubtypeReceive && block.Account == accounts[0].Address() {
+			return failed
+		}
+		return nil
+	}
+	w.DryRun = true
+	// The destination is the last account, spelt with the xrb_ prefix.
+	destination := "xrb_" + strings.TrimPrefix(accounts[2].Address(), "nano_")
+	hashes, err := w.SweepTo(destination)
+	assert.Equal(t, AccountErrors{accounts[0].Address(): failed}, err)
+	assert.Len(t, hashes, 1)
+	var sends []*rpc.Block
+	for _, b := range w.DryRunBlocks() {
+		if b.Link.String() != testFrontier {
+			sends = append(sends, b)
+		}
+	}
+	require.Len(t, sends, 1)
+	assert.Equal(t, accounts[1].Address(), sends[0].Account)
+
+	_, err = w.SweepTo("nano_invalid")
+	assert.NotNil(t, err)
+}
+
+func TestSweepToEmpty(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"accounts_pending": `{"blocks":""}`,
+		"account_info":     `{"error":"Account not found"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	hashes, err := w.SweepTo(testDestination)
+	require.Nil(t, err)
+	assert.Empty(t, hashes)
+	assert.Equal(t, 0, node.called("process"))
+	assert.NotEqual(t, testDestination, a.Address())
+}
+
+func TestReceivable(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	node.responses["accounts_pending"] = `{"blocks":{"` + a.Address() + `":{"` + testFrontier +
+		`":{"amount":"1000","source":"` + testDestination + `"}}}}`
+	pendings, err := w.Receivable(new(big.Int))
+	require.Nil(t, err)
+	require.Len(t, pendings, 1)
+	pending := pendings[a.Address()][testFrontier]
+	assert.Equal(t, "1000", pending.Amount.String())
+	assert.Equal(t, testDestination, pending.Source)
+	assert.Equal(t, 0, node.called("process"))
+}
+
+func TestTotalBalance(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{})
+	confirmed, pending, err := w.TotalBalance()
+	require.Nil(t, err)
+	assert.Zero(t, confirmed.Sign())
+	assert.Zero(t, pending.Sign())
+	assert.Zero(t, node.called("accounts_balances"))
+
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	node.responses["accounts_balances"] = `{"balances":{` +
+		`"` + a.Address() + `":{"balance":"100","pending":"5"},` +
+		`"` + b.Address() + `":{"balance":"20","pending":"1"}}}`
+	confirmed, pending, err = w.TotalBalance()
+	require.Nil(t, err)
+	assert.Equal(t, "120", confirmed.String())
+	assert.Equal(t, "6", pending.String())
+	assert.Equal(t, 1, node.called("accounts_balances"))
+}
+
+func TestBananoDefaults(t *testing.T) {
+	w, err := NewBananoWallet(make([]byte, 32))
+	require.Nil(t, err)
+	assert.Equal(t, "fffffe0000000000", w.WorkDifficulty.String())
+	assert.Equal(t, "fffffe0000000000", w.ReceiveWorkDifficulty.String())
+	raw, err := w.ParseAmount("1.5")
+	require.Nil(t, err)
+	assert.Equal(t, "150000000000000000000000000000", raw.String())
+	assert.Equal(t, "1.500000", w.FormatAmount(raw))
+
+	w, err = NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	assert.Equal(t, "fffffff800000000", w.WorkDifficulty.String())
+	raw, err = w.ParseAmount("1.5")
+	require.Nil(t, err)
+	assert.Equal(t, "1500000000000000000000000000000", raw.String())
+	assert.Equal(t, "1.500000", w.FormatAmount(raw))
+}
+
+func TestDeriveAccount(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	a, err := w.DeriveAccount(5)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(5), a.Index())
+	assert.Nil(t, w.GetAccount(a.Address()))
+	assert.Empty(t, w.GetAccounts())
+
+	index := uint32(5)
+	b, err := w.NewAccount(&index)
+	require.Nil(t, err)
+	assert.Equal(t, a.Address(), b.Address())
+}
+
+func TestOwnsAddress(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	a, err := w.DeriveAccount(5)
+	require.Nil(t, err)
+	assert.False(t, w.OwnsAddress(a.Address(), 5))
+	assert.True(t, w.OwnsAddress(a.Address(), 6))
+	assert.True(t, w.OwnsAddress("xrb_"+strings.TrimPrefix(a.Address(), "nano_"), 6))
+	assert.Empty(t, w.GetAccounts())
+	assert.False(t, w.OwnsAddress("nano_invalid", 6))
+
+	index := uint32(100)
+	b, err := w.NewAccount(&index)
+	require.Nil(t, err)
+	assert.True(t, w.OwnsAddress(b.Address(), 0))
+}
+
+func TestLoadAccounts(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	indices := []uint32{7, 2, 11, 2}
+	accounts, err := w.LoadAccounts(indices)
+	require.Nil(t, err)
+	require.Len(t, accounts, len(indices))
+	for i, a := range accounts {
+		assert.Equal(t, indices[i], a.Index())
+		assert.Equal(t, a, w.GetAccount(a.Address()))
+	}
+	assert.Equal(t, accounts[1], accounts[3])
+	assert.Len(t, w.GetAccounts(), 3)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(12), a.Index())
+}
+
+func TestRemoveAccount(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	w.RemoveAccount(a.Address())
+	assert.Nil(t, w.GetAccount(a.Address()))
+	w.RemoveAccount(a.Address())
+}
+
+// newScanServer returns the URL of a node on which pending maps to the
+// addresses with pending funds and opened to those with blocks.
+func newScanServer(t *testing.T, pending, opened map[string]bool) string {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var v struct {
+			Action   string
+			Accounts []string
+		}
+		json.NewDecoder(r.Body).Decode(&v)
+		balances := make(map[string]interface{})
+		frontiers := make(map[string]string)
+		for _, a := range v.Accounts {
+			p := "0"
+			if pending[a] {
+				p = "1"
+			}
+			balances[a] = map[string]string{"balance": "0", "pending": p}
+			if opened[a] {
+				frontiers[a] = testFrontier
+			}
+		}
+		if v.Action == "accounts_balances" {
+			json.NewEncoder(rw).Encode(map[string]interface{}{"balances": balances})
+		} else {
+			json.NewEncoder(rw).Encode(map[string]interface{}{"frontiers": frontiers})
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s.URL
+}
+
+func TestScanForActiveAccounts(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	address := func(index uint32) string {
+		a, err := w.DeriveAccount(index)
+		require.Nil(t, err)
+		return a.Address()
+	}
+	w.RPC.URL = newScanServer(t,
+		map[string]bool{address(2): true},
+		map[string]bool{address(12): true, address(30): true})
+	accounts, err := w.ScanForActiveAccounts(10)
+	require.Nil(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, uint32(2), accounts[0].Index())
+	assert.Equal(t, uint32(12), accounts[1].Index())
+	assert.Len(t, w.GetAccounts(), 2)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(13), a.Index())
+
+	_, err = w.ScanForActiveAccounts(0)
+	assert.NotNil(t, err)
+}
+
+func TestScanForAccounts(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	address := func(index uint32) string {
+		a, err := w.DeriveAccount(index)
+		require.Nil(t, err)
+		return a.Address()
+	}
+	w.RPC.URL = newScanServer(t, map[string]bool{address(7): true}, map[string]bool{address(11): true})
+	require.Nil(t, w.ScanForAccounts())
+	assert.Len(t, w.GetAccounts(), 12)
+	assert.NotNil(t, w.GetAccount(address(11)))
+
+	w2, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	w2.RPC.URL = "http://127.0.0.1:0"
+	assert.NotNil(t, w2.ScanForAccounts())
+	assert.Empty(t, w2.GetAccounts())
+	a, err := w2.NewAccount(nil)
+	require.Nil(t, err)
+	assert.Equal(t, uint32(0), a.Index())
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(`{"balances":{},"frontiers":{}}`))
+	}))
+	defer s.Close()
+	w2.RPC.URL = s.URL
+	assert.NotNil(t, w2.ScanForAccounts())
+	assert.Len(t, w2.GetAccounts(), 1)
+}
+
+func TestNextUnusedAccount(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	opened := make(map[string]bool)
+	for i := uint32(0); i < 12; i++ {
+		if i != 3 {
+			a, err := w.DeriveAccount(i)
+			require.Nil(t, err)
+			opened[a.Address()] = true
+		}
+	}
+	w.RPC.URL = newScanServer(t, nil, opened)
+	a, err := w.NextUnusedAccount()
+	require.Nil(t, err)
+	assert.Equal(t, uint32(3), a.Index())
+	b, err := w.NextUnusedAccount()
+	require.Nil(t, err)
+	assert.Equal(t, a, b)
+
+	opened[a.Address()] = true
+	a, err = w.NextUnusedAccount()
+	require.Nil(t, err)
+	assert.Equal(t, uint32(12), a.Index())
+}
+
+func TestChangeRepAll(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	opened, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	unopened, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var v struct{ Action, Account string }
+		json.NewDecoder(r.Body).Decode(&v)
+		switch {
+		case v.Action == "work_generate":
+			rw.Write([]byte(`{"work":"0000000000000000"}`))
+		case v.Account == opened.Address():
+			rw.Write([]byte(`{"frontier":"` + testFrontier + `","balance":"1000"}`))
+		default:
+			rw.Write([]byte(`{"error":"Account not found"}`))
+		}
+	}))
+	defer s.Close()
+	w.RPC.URL, w.RPCWork.URL = s.URL, s.URL
+	w.DryRun = true
+	hashes, err := w.ChangeRepAll(testDestination)
+	require.Nil(t, err)
+	assert.Len(t, hashes, 1)
+	assert.NotNil(t, hashes[opened.Address()])
+	assert.Nil(t, hashes[unopened.Address()])
+	require.Len(t, w.DryRunBlocks(), 1)
+
+	w.RPC.URL = "http://127.0.0.1:0"
+	_, err = w.ChangeRepAll(testDestination)
+	var errs AccountErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+}
+
+func TestWaitForConfirmation(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"block_confirm": `{"started":"1"}`,
+		"block_info":    `{"confirmed":"true"}`,
+	})
+	hash, _ := rpc.ParseBlockHash(testFrontier)
+	require.Nil(t, w.WaitForConfirmation(context.Background(), hash))
+	assert.Equal(t, 0, node.called("block_confirm"))
+	w.ConfirmationStrategy = ConfirmByRequest
+	require.Nil(t, w.WaitForConfirmation(context.Background(), hash))
+	assert.Equal(t, 1, node.called("block_confirm"))
+
+	node.responses["block_info"] = `{"confirmed":"false"}`
+	w.ConfirmationStrategy = ConfirmByPolling
+	w.ConfirmationPollInterval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.WaitForConfirmation(ctx, hash))
+
+	w.ConfirmationPollInterval = -time.Second
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+	assert.Equal(t, context.DeadlineExceeded, w.WaitForConfirmation(ctx2, hash))
+}
+
+func TestWaitForConfirmationWebsocket(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{"block_info": `{"confirmed":"false"}`})
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(rw, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var v map[string]interface{}
+		c.ReadJSON(&v)
+		for _, hash := range []string{"96D8422D1CB676EF1B62A313865626A7725C3B9BB5B875601A1460ACF30B5322", testFrontier} {
+			c.WriteMessage(websocket.TextMessage, []byte(`{"topic":"confirmation","time":"1587109495082",`+
+				`"message":{"hash":"`+hash+`","confirmation_type":"active_quorum"}}`))
+		}
+		c.ReadMessage()
+	}))
+	defer s.Close()
+	w.ConfirmationStrategy = ConfirmByWebsocket
+	w.WebsocketURL = "ws" + strings.TrimPrefix(s.URL, "http")
+	hash, _ := rpc.ParseBlockHash(testFrontier)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Nil(t, w.WaitForConfirmation(ctx, hash))
+}
+
+func TestReceivePendingsContext(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	node.responses["accounts_pending"] = `{"blocks":{"` + a.Address() + `":{"` + testFrontier + `":{"amount":"1"}}}}`
+	w.DryRun = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = w.ReceivePendingsContext(ctx, new(big.Int))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, AccountErrors{a.Address(): context.Canceled}, err)
+	assert.Equal(t, 0, node.called("account_info"))
+	require.Nil(t, w.ReceivePendingsContext(context.Background(), new(big.Int)))
+	assert.Len(t, w.DryRunBlocks(), 1)
+}
+
+func TestReceivePendingsContextErrors(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	b, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	node.responses["accounts_pending"] = `{"blocks":{"` + a.Address() + `":{"` + testFrontier + `":{"amount":"1"}},` +
+		`"` + b.Address() + `":{"` + testFrontier + `":{"amount":"1"}}}}`
+	w.DryRun = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errFailed := errors.New("failed")
+	// a fails and cancels ctx, while b is held up until ctx is done.
+	w.BeforeBroadcast = func(block *rpc.Block, subtype rpc.BlockSubtype) error {
+		if block.Account == a.Address() {
+			cancel()
+			return errFailed
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	err = w.ReceivePendingsContext(ctx, new(big.Int))
+	assert.True(t, errors.Is(err, context.Canceled))
+	var errs AccountErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.True(t, errors.Is(errs[a.Address()], errFailed))
+	assert.True(t, errors.Is(errs[b.Address()], context.Canceled))
+}
+
+func TestReceivePendingsConcurrent(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	var addresses []string
+	for i := 0; i < 6; i++ {
+		a, err := w.NewAccount(nil)
+		require.Nil(t, err)
+		addresses = append(addresses, a.Address())
+	}
+	failing := addresses[2]
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var v struct{ Action, Account string }
+		json.NewDecoder(r.Body).Decode(&v)
+		switch {
+		case v.Action == "work_generate":
+			rw.Write([]byte(`{"work":"0000000000000000"}`))
+		case v.Action == "accounts_pending":
+			blocks := make(map[string]interface{})
+			for _, a := range addresses {
+				blocks[a] = map[string]interface{}{testFrontier: map[string]string{"amount": "1"}}
+			}
+			json.NewEncoder(rw).Encode(map[string]interface{}{"blocks": blocks})
+		case v.Account == failing:
+			rw.Write([]byte(`{"error":"Internal server error"}`))
+		default:
+			rw.Write([]byte(`{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`))
+		}
+	}))
+	defer s.Close()
+	w.RPC.URL, w.RPCWork.URL = s.URL, s.URL
+	w.DryRun = true
+	err = w.ReceivePendings(new(big.Int))
+	var errs AccountErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.NotNil(t, errs[failing])
+	assert.Len(t, w.DryRunBlocks(), 5)
+}
+
+func TestReceivePendingsLimit(t *testing.T) {
+	w, err := NewWallet(make([]byte, 32))
+	require.Nil(t, err)
+	busy, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	quiet, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	hash := func(i int) string { return fmt.Sprintf("%064X", i) }
+	var count float64
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var v map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&v)
+		switch v["action"] {
+		case "work_generate":
+			rw.Write([]byte(`{"work":"0000000000000000"}`))
+		case "accounts_pending":
+			count = v["count"].(float64)
+			blocks := map[string]interface{}{}
+			for i := 1; i <= 4; i++ {
+				blocks[hash(i)] = map[string]string{"amount": fmt.Sprint(i)}
+			}
+			json.NewEncoder(rw).Encode(map[string]interface{}{"blocks": map[string]interface{}{
+				busy.Address():  blocks,
+				quiet.Address(): map[string]interface{}{hash(5): map[string]string{"amount": "1"}},
+			}})
+		default:
+			rw.Write([]byte(`{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`))
+		}
+	}))
+	defer s.Close()
+	w.RPC.URL, w.RPCWork.URL = s.URL, s.URL
+	w.DryRun = true
+	require.Nil(t, w.ReceivePendingsLimit(context.Background(), nil, 3))
+	assert.Equal(t, 3.0, count)
+	links := make(map[string]string)
+	for _, b := range w.DryRunBlocks() {
+		links[b.Link.String()] = b.Account
+	}
+	assert.Equal(t, map[string]string{
+		hash(4): busy.Address(),
+		hash(3): busy.Address(),
+		hash(5): quiet.Address(),
+	}, links)
+	assert.NotNil(t, w.ReceivePendingsLimit(context.Background(), nil, 0))
+}
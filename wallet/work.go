@@ -1,23 +1,237 @@
 package wallet
 
 import (
-	"encoding/hex"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 
 	"github.com/hectorchu/gonano/pow"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/hectorchu/gonano/util"
 )
 
-func (w *Wallet) workGenerate(data []byte) (work []byte, err error) {
-	difficulty, _ := hex.DecodeString(w.WorkDifficulty)
-	if work, _, _, err = w.RPCWork.WorkGenerate(data, difficulty); err == nil {
+func (w *Wallet) workGenerate(ctx context.Context, data []byte) (work []byte, err error) {
+	return w.generateWork(ctx, data, w.WorkDifficulty)
+}
+
+// epoch1Difficulty is the work threshold of all blocks of accounts that
+// haven't been upgraded to epoch 2, receives included.
+var epoch1Difficulty = rpc.HexDataFromUint64(0xffffffc000000000)
+
+// workGenerateReceive generates work for a receive. preEpoch2 is set for
+// opened accounts below epoch 2, whose receives are held to the epoch 1
+// threshold unless the pocketed send is itself an epoch 2 block. Since that
+// isn't known here, the higher threshold is always used for such accounts.
+func (w *Wallet) workGenerateReceive(ctx context.Context, data []byte, preEpoch2 bool) (work []byte, err error) {
+	difficulty := w.ReceiveWorkDifficulty
+	raise := func(d rpc.HexData) {
+		if len(d) == 8 && d.Uint64() > difficulty.Uint64() {
+			difficulty = d
+		}
+	}
+	if preEpoch2 && !w.isBanano {
+		raise(epoch1Difficulty)
+	}
+	if !w.UseNetworkReceiveDifficulty {
+		return w.generateWork(ctx, data, difficulty)
+	}
+	if d, err := w.RPC.ActiveDifficulty(); err == nil {
+		raise(d.NetworkReceiveCurrent)
+	}
+	if work, err = w.generateWork(ctx, data, difficulty); err != nil {
 		return
 	}
-	return pow.Generate(data, difficulty)
+	if !pow.Validate(data, work, difficulty) {
+		// The work server didn't honour the difficulty, redo it on the CPU.
+		return w.generateWorkCPU(data, difficulty)
+	}
+	return
+}
+
+// WorkProvider generates proof-of-work for the wallet. data is the hash the
+// work is for. The work is returned in the byte order of a block's work
+// field.
+type WorkProvider interface {
+	GenerateWork(ctx context.Context, data []byte, difficulty rpc.HexData) (work []byte, err error)
 }
 
-func (w *Wallet) workGenerateReceive(data []byte) (work []byte, err error) {
-	difficulty, _ := hex.DecodeString(w.ReceiveWorkDifficulty)
-	if work, _, _, err = w.RPCWork.WorkGenerate(data, difficulty); err == nil {
+// RPCWorkProvider generates work with work_generate on a node or work server.
+type RPCWorkProvider struct {
+	Client rpc.Client
+	// UsePeers asks the node to use its configured work peers.
+	UsePeers bool
+}
+
+// GenerateWork implements WorkProvider.
+func (p RPCWorkProvider) GenerateWork(ctx context.Context, data []byte, difficulty rpc.HexData) (work []byte, err error) {
+	c := p.Client
+	c.Ctx = ctx
+	work, _, _, err = c.WorkGenerateWithOptions(data, rpc.WorkGenerateOptions{Difficulty: difficulty, UsePeers: p.UsePeers})
+	return
+}
+
+// String returns the URL of the work server, the source of its work as
+// reported to Wallet.OnWorkGenerated.
+func (p RPCWorkProvider) String() string {
+	return p.Client.URL
+}
+
+// CPUWorkProvider generates work on the CPU, within the limit of the
+// wallet's CPUWorkConcurrency when used as one of its WorkProviders. ctx is
+// only checked before generation starts.
+type CPUWorkProvider struct{}
+
+// GenerateWork implements WorkProvider.
+func (CPUWorkProvider) GenerateWork(ctx context.Context, data []byte, difficulty rpc.HexData) (work []byte, err error) {
+	if err = ctx.Err(); err != nil {
 		return
 	}
 	return pow.Generate(data, difficulty)
 }
+
+// String returns WorkSourceCPU.
+func (CPUWorkProvider) String() string {
+	return WorkSourceCPU
+}
+
+// generateWork uses WorkProviders if set. Otherwise it tries RPCWork
+// followed by each of WorkServers in order, falling back to generating the
+// work on the CPU if none of them succeed. Work servers without a URL are
+// skipped.
+func (w *Wallet) generateWork(ctx context.Context, data []byte, difficulty rpc.HexData) (work []byte, err error) {
+	if len(w.WorkProviders) > 0 {
+		return w.generateWorkProviders(ctx, data, difficulty)
+	}
+	opts := rpc.WorkGenerateOptions{Difficulty: difficulty, UsePeers: w.UseWorkPeers}
+	for _, c := range append([]rpc.Client{w.RPCWork}, w.WorkServers...) {
+		if c.URL == "" {
+			continue
+		}
+		if c.Ctx == nil {
+			c.Ctx = ctx
+		}
+		if work, _, _, err = c.WorkGenerateWithOptions(data, opts); err == nil {
+			w.workGenerated(data, work, c.URL)
+			return
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return w.generateWorkCPU(data, difficulty)
+}
+
+// generateWorkProviders returns the work of the first of WorkProviders to
+// succeed, or the error of the last one. The context given to the providers
+// is cancelled once the work is generated, so that providers can stop any
+// work still in progress.
+func (w *Wallet) generateWorkProviders(ctx context.Context, data []byte, difficulty rpc.HexData) (work []byte, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for _, p := range w.WorkProviders {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		switch p.(type) {
+		case CPUWorkProvider, *CPUWorkProvider:
+			release := w.acquireCPUWork()
+			work, err = p.GenerateWork(ctx, data, difficulty)
+			release()
+		default:
+			work, err = p.GenerateWork(ctx, data, difficulty)
+		}
+		if err == nil {
+			source := fmt.Sprintf("%T", p)
+			if s, ok := p.(fmt.Stringer); ok {
+				source = s.String()
+			}
+			w.workGenerated(data, work, source)
+			return
+		}
+	}
+	return
+}
+
+// WorkSourceCPU is the source given to Wallet.OnWorkGenerated for work
+// generated on the CPU.
+const WorkSourceCPU = "cpu"
+
+func (w *Wallet) generateWorkCPU(data, difficulty []byte) (work []byte, err error) {
+	defer w.acquireCPUWork()()
+	if work, err = pow.Generate(data, difficulty); err == nil {
+		w.workGenerated(data, work, WorkSourceCPU)
+	}
+	return
+}
+
+// acquireCPUWork waits until CPU work can be generated within the limit of
+// CPUWorkConcurrency, returning the function that releases it again.
+func (w *Wallet) acquireCPUWork() (release func()) {
+	if w.CPUWorkConcurrency <= 0 {
+		return func() {}
+	}
+	w.cpuWorkSemOnce.Do(func() { w.cpuWorkSem = make(chan struct{}, w.CPUWorkConcurrency) })
+	w.cpuWorkSem <- struct{}{}
+	return func() { <-w.cpuWorkSem }
+}
+
+func (w *Wallet) workGenerated(data, work []byte, source string) {
+	if w.OnWorkGenerated != nil {
+		w.OnWorkGenerated(data, work, source)
+	}
+}
+
+// validateWork checks that the work of block meets the wallet's difficulty
+// for subtype. The work of open blocks is for the account's public key, and
+// for the previous block otherwise.
+func (w *Wallet) validateWork(block *rpc.Block, subtype rpc.BlockSubtype) (err error) {
+	data := []byte(block.Previous)
+	if bytes.Equal(data, make([]byte, 32)) {
+		if data, err = util.AddressToPubkey(block.Account); err != nil {
+			return
+		}
+	}
+	difficulty := w.WorkDifficulty
+	if subtype == rpc.SubtypeReceive || subtype == rpc.SubtypeOpen {
+		difficulty = w.ReceiveWorkDifficulty
+	}
+	if !pow.Validate(data, block.Work, difficulty) {
+		return fmt.Errorf("work %s is invalid for difficulty %s", block.WorkHex(), difficulty)
+	}
+	return
+}
+
+// RebroadcastWithWork replaces the work of block with work generated at
+// difficulty, and publishes it again. It is for blocks whose work has become
+// too low for the network to confirm them promptly. The block doesn't need
+// to be signed again, as work isn't part of its hash. If difficulty is nil,
+// the wallet's difficulty for the kind of block is used. The node reporting
+// the block as old is not an error, as it already has every such block.
+func (w *Wallet) RebroadcastWithWork(block *rpc.Block, difficulty rpc.HexData) (hash rpc.BlockHash, err error) {
+	subtype, err := w.RPC.DetectSubtype(block)
+	if err != nil {
+		return
+	}
+	data := []byte(block.Previous)
+	if bytes.Equal(data, make([]byte, 32)) {
+		if data, err = util.AddressToPubkey(block.Account); err != nil {
+			return
+		}
+	}
+	if difficulty == nil {
+		difficulty = w.WorkDifficulty
+		if subtype == rpc.SubtypeReceive || subtype == rpc.SubtypeOpen {
+			difficulty = w.ReceiveWorkDifficulty
+		}
+	}
+	b := *block
+	if b.Work, err = w.generateWork(context.Background(), data, difficulty); err != nil {
+		return
+	}
+	if hash, err = w.process(&b, subtype); errors.Is(err, rpc.ErrOldBlock) {
+		// The node already has the block, so it only takes the new work.
+		return b.Hash()
+	}
+	return
+}
@@ -0,0 +1,306 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hectorchu/gonano/pow"
+	"github.com/hectorchu/gonano/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkServers(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{})
+	w.RPCWork.URL = "http://127.0.0.1:1"
+	bad := &fakeNode{responses: map[string]string{"work_generate": `{"error":"Cancelled"}`}}
+	good := &fakeNode{responses: map[string]string{"work_generate": `{"work":"0123456789abcdef"}`}}
+	for _, n := range []*fakeNode{bad, good} {
+		s := httptest.NewServer(n)
+		t.Cleanup(s.Close)
+		w.WorkServers = append(w.WorkServers, rpc.Client{URL: s.URL})
+	}
+	work, err := w.workGenerate(context.Background(), make([]byte, 32))
+	require.Nil(t, err)
+	assert.Equal(t, "0123456789abcdef", hex.EncodeToString(work))
+	assert.Equal(t, 1, bad.called("work_generate"))
+	assert.Equal(t, 1, good.called("work_generate"))
+}
+
+func TestWorkCPUOnly(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{})
+	w.RPCWork.URL = ""
+	w.WorkDifficulty = rpc.HexDataFromUint64(0xff00000000000000)
+	work, err := w.workGenerate(context.Background(), make([]byte, 32))
+	require.Nil(t, err)
+	assert.Len(t, work, 8)
+	assert.Equal(t, 0, node.called("work_generate"))
+}
+
+func TestNetworkReceiveDifficulty(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"active_difficulty": `{"network_receive_current":"ff80000000000000"}`,
+		"work_generate":     `{"work":"0000000000000000"}`,
+	})
+	w.ReceiveWorkDifficulty = rpc.HexDataFromUint64(0xff00000000000000)
+	w.UseNetworkReceiveDifficulty = true
+	data := make([]byte, 32)
+	work, err := w.workGenerateReceive(context.Background(), data, false)
+	require.Nil(t, err)
+	assert.True(t, pow.Validate(data, work, []byte{0xff, 0x80, 0, 0, 0, 0, 0, 0}))
+	assert.Equal(t, 1, node.called("active_difficulty"))
+
+	var body map[string]interface{}
+	w.RPCWork = rpc.Client{URL: httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		rw.Write([]byte(`{"error":"Cancelled"}`))
+	})).URL}
+	_, err = w.workGenerateReceive(context.Background(), data, false)
+	require.Nil(t, err)
+	assert.Equal(t, "ff80000000000000", body["difficulty"])
+}
+
+func TestValidateWork(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","representative":"` + testDestination + `"}`,
+	})
+	w.ValidateWork = true
+	w.WorkDifficulty = rpc.HexDataFromUint64(0xff00000000000000)
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	_, err = a.Send(testDestination, big.NewInt(1))
+	assert.EqualError(t, err, "work 0000000000000000 is invalid for difficulty ff00000000000000")
+	assert.Equal(t, 0, node.called("process"))
+
+	w.RPCWork.URL = ""
+	_, err = a.Send(testDestination, big.NewInt(1))
+	require.Nil(t, err)
+	assert.Equal(t, 1, node.called("process"))
+}
+
+func TestEpoch1ReceiveDifficulty(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"frontier":"` + testFrontier + `","balance":"1000","account_version":"1"}`,
+	})
+	var difficulties []interface{}
+	w.RPCWork = rpc.Client{URL: httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		difficulties = append(difficulties, body["difficulty"])
+		rw.Write([]byte(`{"work":"0000000000000000"}`))
+	})).URL}
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	link, _ := rpc.ParseBlockHash(testFrontier)
+	_, err = a.ReceivePendingAmount(link, big.NewInt(1))
+	require.Nil(t, err)
+
+	_, err = w.workGenerateReceive(context.Background(), make([]byte, 32), false)
+	require.Nil(t, err)
+	w.isBanano = true
+	_, err = w.workGenerateReceive(context.Background(), make([]byte, 32), true)
+	require.Nil(t, err)
+	assert.Equal(t, []interface{}{"ffffffc000000000", "fffffe0000000000", "fffffe0000000000"}, difficulties)
+}
+
+func TestOpenReceiveDifficulty(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{
+		"account_info": `{"error":"Account not found"}`,
+	})
+	var difficulties []interface{}
+	w.RPCWork = rpc.Client{URL: httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		difficulties = append(difficulties, body["difficulty"])
+		rw.Write([]byte(`{"work":"0000000000000000"}`))
+	})).URL}
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	err = a.receivePendings(context.Background(), rpc.HashToPendingMap{
+		testFrontier: {Amount: &rpc.RawAmount{}},
+		"96D8422D1CB676EF1B62A313865626A7725C3B9BB5B875601A1460ACF30B5322": {Amount: &rpc.RawAmount{}},
+		"991CF190094C00F0B68E2E5F75F6BEE95A2E0BD93CEAA4A6734DB9F19B728948": {Amount: &rpc.RawAmount{}},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []interface{}{"fffffe0000000000", "fffffe0000000000", "fffffe0000000000"}, difficulties)
+}
+
+func TestRebroadcastWithWork(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{
+		"block_info":    `{"balance":"1000","subtype":"receive"}`,
+		"work_generate": `{"work":"0123456789abcdef"}`,
+		"process":       `{"error":"Old block"}`,
+	})
+	var subtypes []rpc.BlockSubtype
+	w.BeforeBroadcast = func(block *rpc.Block, subtype rpc.BlockSubtype) error {
+		assert.Equal(t, "0123456789abcdef", block.WorkHex())
+		subtypes = append(subtypes, subtype)
+		return nil
+	}
+	a, err := w.NewAccount(nil)
+	require.Nil(t, err)
+	previous, _ := rpc.ParseBlockHash(testFrontier)
+	block := &rpc.Block{
+		Type:           "state",
+		Account:        a.Address(),
+		Previous:       previous,
+		Representative: testDestination,
+		Balance:        &rpc.RawAmount{Int: *big.NewInt(999)},
+		Link:           make(rpc.BlockHash, 32),
+		Work:           make(rpc.HexData, 8),
+	}
+	expected, err := block.Hash()
+	require.Nil(t, err)
+	hash, err := w.RebroadcastWithWork(block, DefaultSendDifficulty)
+	require.Nil(t, err)
+	assert.Equal(t, expected, hash)
+	assert.Equal(t, "0000000000000000", block.WorkHex())
+	assert.Equal(t, 1, node.called("process"))
+
+	block.Balance, block.Link = &rpc.RawAmount{Int: *big.NewInt(1001)}, previous
+	_, err = w.RebroadcastWithWork(block, nil)
+	require.Nil(t, err)
+	block.Balance, block.Link = &rpc.RawAmount{Int: *big.NewInt(1000)}, make(rpc.BlockHash, 32)
+	_, err = w.RebroadcastWithWork(block, nil)
+	require.Nil(t, err)
+	node.responses["process"] = `{"error":"Fork"}`
+	_, err = w.RebroadcastWithWork(block, nil)
+	assert.True(t, errors.Is(err, rpc.ErrFork))
+	assert.Equal(t, []rpc.BlockSubtype{rpc.SubtypeSend, rpc.SubtypeReceive, rpc.SubtypeChange, rpc.SubtypeChange}, subtypes)
+}
+
+func TestOnWorkGenerated(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{})
+	var sources []string
+	w.OnWorkGenerated = func(data, work []byte, source string) {
+		assert.Len(t, work, 8)
+		sources = append(sources, source)
+	}
+	_, err := w.workGenerate(context.Background(), make([]byte, 32))
+	require.Nil(t, err)
+	url := w.RPCWork.URL
+	w.RPCWork.URL = ""
+	w.WorkDifficulty = rpc.HexDataFromUint64(0xff00000000000000)
+	_, err = w.workGenerate(context.Background(), make([]byte, 32))
+	require.Nil(t, err)
+	assert.Equal(t, []string{url, WorkSourceCPU}, sources)
+}
+
+func TestCPUWorkConcurrency(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{})
+	w.CPUWorkConcurrency = 2
+	var (
+		wg               sync.WaitGroup
+		mutex            sync.Mutex
+		running, maximum int
+	)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := w.acquireCPUWork()
+			mutex.Lock()
+			if running++; running > maximum {
+				maximum = running
+			}
+			mutex.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, maximum)
+
+	w.RPCWork.URL = ""
+	w.WorkDifficulty = rpc.HexDataFromUint64(0xff00000000000000)
+	_, err := w.workGenerate(context.Background(), make([]byte, 32))
+	require.Nil(t, err)
+	assert.Len(t, w.cpuWorkSem, 0)
+}
+
+type failingWorkProvider struct{ calls int }
+
+func (p *failingWorkProvider) GenerateWork(ctx context.Context, data []byte, difficulty rpc.HexData) ([]byte, error) {
+	p.calls++
+	return nil, errors.New("no work")
+}
+
+func TestWorkProviders(t *testing.T) {
+	w, node := newTestWallet(t, map[string]string{})
+	failing := &failingWorkProvider{}
+	w.WorkDifficulty = rpc.HexDataFromUint64(0xff00000000000000)
+	w.WorkProviders = []WorkProvider{failing, CPUWorkProvider{}, RPCWorkProvider{Client: w.RPCWork}}
+	var sources []string
+	w.OnWorkGenerated = func(data, work []byte, source string) { sources = append(sources, source) }
+	data := make([]byte, 32)
+	work, err := w.workGenerate(context.Background(), data)
+	require.Nil(t, err)
+	assert.True(t, pow.Validate(data, work, []byte{0xff, 0, 0, 0, 0, 0, 0, 0}))
+	assert.Equal(t, 1, failing.calls)
+	assert.Equal(t, []string{WorkSourceCPU}, sources)
+	assert.Zero(t, node.called("work_generate"))
+
+	w.WorkProviders = []WorkProvider{RPCWorkProvider{Client: w.RPCWork}}
+	_, err = w.workGenerate(context.Background(), data)
+	require.Nil(t, err)
+	assert.Equal(t, 1, node.called("work_generate"))
+	assert.Equal(t, w.RPCWork.URL, sources[1])
+
+	w.WorkProviders = []WorkProvider{failing}
+	_, err = w.workGenerate(context.Background(), data)
+	assert.EqualError(t, err, "no work")
+}
+
+type contextWorkProvider struct{ ctx context.Context }
+
+func (p *contextWorkProvider) GenerateWork(ctx context.Context, data []byte, difficulty rpc.HexData) ([]byte, error) {
+	p.ctx = ctx
+	return make([]byte, 8), nil
+}
+
+func TestWorkProvidersContext(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{})
+	p := &contextWorkProvider{}
+	w.WorkProviders = []WorkProvider{p}
+	_, err := w.workGenerate(context.Background(), make([]byte, 32))
+	require.Nil(t, err)
+	assert.Equal(t, context.Canceled, p.ctx.Err())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.ctx = nil
+	_, err = w.workGenerate(ctx, make([]byte, 32))
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, p.ctx)
+}
+
+func TestWorkProvidersCPUConcurrency(t *testing.T) {
+	w, _ := newTestWallet(t, map[string]string{})
+	w.CPUWorkConcurrency = 1
+	w.WorkDifficulty = rpc.HexDataFromUint64(0xff00000000000000)
+	w.WorkProviders = []WorkProvider{&CPUWorkProvider{}}
+	release := w.acquireCPUWork()
+	done := make(chan error)
+	go func() {
+		_, err := w.workGenerate(context.Background(), make([]byte, 32))
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("work generated beyond CPUWorkConcurrency")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	require.Nil(t, <-done)
+}